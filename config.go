@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/jplot/data"
+	"gopkg.in/yaml.v3"
+)
+
+// config describes a dashboard in a YAML or JSON file. Its settings mirror
+// the command line flags, which take precedence when both are provided.
+type config struct {
+	Source  sourceConfig      `yaml:"source"`
+	Sources map[string]string `yaml:"sources"`
+	Record  string            `yaml:"record"`
+	Steps   int               `yaml:"steps"`
+	History int               `yaml:"history"`
+	Rows    int               `yaml:"rows"`
+	Text    bool              `yaml:"text"`
+	Output  string            `yaml:"output"`
+	Size    string            `yaml:"size"`
+	HTTP    string            `yaml:"http"`
+	Graphs  []graphConfig     `yaml:"graphs"`
+}
+
+type sourceConfig struct {
+	URL         string            `yaml:"url"`
+	Exec        string            `yaml:"exec"`
+	File        string            `yaml:"file"`
+	Backfill    bool              `yaml:"backfill"`
+	Replay      string            `yaml:"replay"`
+	ReplaySpeed *float64          `yaml:"replay_speed"`
+	Format      string            `yaml:"format"`
+	Interval    time.Duration     `yaml:"interval"`
+	Retries     *int              `yaml:"retries"`
+	Backoff     time.Duration     `yaml:"backoff"`
+	Timeout     time.Duration     `yaml:"timeout"`
+	Method      string            `yaml:"method"`
+	Data        string            `yaml:"data"`
+	Headers     map[string]string `yaml:"headers"`
+	BearerToken string            `yaml:"bearer_token"`
+	User        string            `yaml:"user"`
+	Cert        string            `yaml:"cert"`
+	Key         string            `yaml:"key"`
+	CACert      string            `yaml:"cacert"`
+	TimeField   string            `yaml:"time_field"`
+	Aggregate   bool              `yaml:"aggregate"`
+}
+
+type graphConfig struct {
+	Title  string        `yaml:"title"`
+	Height int           `yaml:"height"`
+	Fields []fieldConfig `yaml:"fields"`
+}
+
+// fieldConfig is either a field spec as given on the command line
+// (eg: counter:memstats.NumGC) or an object.
+type fieldConfig struct {
+	Path    string   `yaml:"path"`
+	Options []string `yaml:"options"`
+	Label   string   `yaml:"label"`
+	Color   string   `yaml:"color"`
+
+	spec string
+}
+
+var hexColor = regexp.MustCompile(`^#?([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+func (f *fieldConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		return value.Decode(&f.spec)
+	}
+	type plain fieldConfig
+	if err := value.Decode((*plain)(f)); err != nil {
+		return err
+	}
+	if f.Path == "" {
+		return fmt.Errorf("line %d: missing field path", value.Line)
+	}
+	if f.Color != "" && !hexColor.MatchString(f.Color) {
+		return fmt.Errorf("line %d: invalid color: %s", value.Line, f.Color)
+	}
+	f.spec = f.Path
+	if len(f.Options) > 0 {
+		f.spec = strings.Join(f.Options, ",") + ":" + f.Path
+	}
+	return nil
+}
+
+// loadConfig reads the config file at path. As JSON is valid YAML, both
+// formats are supported.
+func loadConfig(path string) (*config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	c := &config{}
+	if err := dec.Decode(c); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return c, nil
+}
+
+// flags returns the values of the command line flags set by the config.
+func (c *config) flags() map[string]string {
+	flags := map[string]string{}
+	set := func(name, value string, isSet bool) {
+		if isSet {
+			flags[name] = value
+		}
+	}
+	s := c.Source
+	set("url", s.URL, s.URL != "")
+	set("exec", s.Exec, s.Exec != "")
+	set("file", s.File, s.File != "")
+	set("backfill", "true", s.Backfill)
+	set("replay", s.Replay, s.Replay != "")
+	if s.ReplaySpeed != nil {
+		flags["replay-speed"] = strconv.FormatFloat(*s.ReplaySpeed, 'f', -1, 64)
+	}
+	set("format", s.Format, s.Format != "")
+	set("interval", s.Interval.String(), s.Interval != 0)
+	if s.Retries != nil {
+		flags["retries"] = strconv.Itoa(*s.Retries)
+	}
+	set("backoff", s.Backoff.String(), s.Backoff != 0)
+	set("timeout", s.Timeout.String(), s.Timeout != 0)
+	set("method", s.Method, s.Method != "")
+	set("data", s.Data, s.Data != "")
+	set("bearer-token", s.BearerToken, s.BearerToken != "")
+	set("user", s.User, s.User != "")
+	set("cert", s.Cert, s.Cert != "")
+	set("key", s.Key, s.Key != "")
+	set("cacert", s.CACert, s.CACert != "")
+	set("time-field", s.TimeField, s.TimeField != "")
+	set("aggregate", "true", s.Aggregate)
+	set("record", c.Record, c.Record != "")
+	set("steps", strconv.Itoa(c.Steps), c.Steps != 0)
+	set("history", strconv.Itoa(c.History), c.History != 0)
+	set("rows", strconv.Itoa(c.Rows), c.Rows != 0)
+	set("text", "true", c.Text)
+	set("output", c.Output, c.Output != "")
+	set("size", c.Size, c.Size != "")
+	set("http", c.HTTP, c.HTTP != "")
+	return flags
+}
+
+// specs returns the graph specs described by the config.
+func (c *config) specs() ([]data.Spec, error) {
+	args := make([]string, 0, len(c.Graphs))
+	for i, g := range c.Graphs {
+		if len(g.Fields) == 0 {
+			return nil, fmt.Errorf("graph %d: no fields", i+1)
+		}
+		fields := make([]string, 0, len(g.Fields))
+		for _, f := range g.Fields {
+			fields = append(fields, f.spec)
+		}
+		args = append(args, strings.Join(fields, "+"))
+	}
+	specs, err := data.ParseSpec(args)
+	if err != nil {
+		return nil, err
+	}
+	for i, g := range c.Graphs {
+		specs[i].Title = g.Title
+		specs[i].Height = g.Height
+		for j, f := range g.Fields {
+			if j < len(specs[i].Fields) {
+				specs[i].Fields[j].Label = f.Label
+				specs[i].Fields[j].Color = f.Color
+			}
+		}
+	}
+	return specs, nil
+}
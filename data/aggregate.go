@@ -0,0 +1,345 @@
+package data
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elgs/gojq"
+)
+
+// aggregateStats are the statistics computed when the last segment of an
+// aggregated path is a pattern.
+var aggregateStats = []string{"count", "sum", "avg", "min", "max", "p50", "p90", "p95", "p99"}
+
+// eventSeries holds the values of an event field gathered during a bucket.
+type eventSeries struct {
+	path   string   // path of the value in events
+	keys   []string // path of the statistics in the aggregate
+	stats  map[string]bool
+	values []float64
+	// hist counts the events by value. Only the values requested by the
+	// specs or matching one of histPatterns are counted, and are reported in
+	// following buckets with a zero count if absent.
+	hist         map[string]float64
+	histPatterns []*regexp.Regexp
+}
+
+// eventSource aggregates events read one JSON object per line into one
+// sample per interval.
+type eventSource struct {
+	c      chan res
+	done   chan struct{}
+	closer sync.Once
+
+	mu     sync.Mutex
+	count  float64
+	series map[string]*eventSeries
+}
+
+// FromStdinEvents reads raw events from stdin as one JSON object per line and
+// aggregates them every interval, producing a sample with the statistics
+// referenced by specs:
+//   - count: the number of events,
+//   - path.count, path.sum, path.avg, path.min, path.max: the statistic of the
+//     numeric values at path,
+//   - path.p95 (or any other percentile): the percentile of the values at path,
+//   - path.hist.value: the number of events having value at path.
+func FromStdinEvents(specs []Spec, interval time.Duration, size int) (*Points, error) {
+	e, err := newEventSource(os.Stdin, specs, interval)
+	if err != nil {
+		return nil, err
+	}
+	return &Points{
+		Size:   size,
+		Source: e,
+	}, nil
+}
+
+func newEventSource(r io.Reader, specs []Spec, interval time.Duration) (*eventSource, error) {
+	e := &eventSource{
+		c:      make(chan res),
+		done:   make(chan struct{}),
+		series: map[string]*eventSeries{},
+	}
+	for _, spec := range specs {
+		for _, f := range spec.Fields {
+			if f.Source != "" {
+				// Read from another source.
+				continue
+			}
+			paths := []string{f.Name}
+			if f.expr != nil {
+				paths = exprPaths(f.expr)
+			}
+			for _, path := range paths {
+				if err := e.add(path); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	eof := make(chan error, 1)
+	go func() {
+		eof <- e.read(r)
+	}()
+	go e.run(interval, eof)
+	return e, nil
+}
+
+// add registers the statistic referenced by the aggregated path.
+func (e *eventSource) add(path string) error {
+	parts := split(path, '.')
+	if len(parts) == 1 && parts[0] == "count" {
+		return nil
+	}
+	n := len(parts)
+	stat, value := parts[n-1], parts[:n-1]
+	hist := ""
+	if n >= 3 && unquote(parts[n-2]) == "hist" {
+		stat, hist, value = "hist", parts[n-1], parts[:n-2]
+	}
+	if len(value) == 0 {
+		return fmt.Errorf("cannot aggregate %s: missing statistic (eg: %s.avg)", path, path)
+	}
+	s := e.series[strings.Join(value, ".")]
+	if s == nil {
+		s = &eventSeries{
+			path:  strings.Join(value, "."),
+			stats: map[string]bool{},
+			hist:  map[string]float64{},
+		}
+		for _, v := range value {
+			s.keys = append(s.keys, unquote(v))
+		}
+		e.series[s.path] = s
+	}
+	switch {
+	case stat == "hist":
+		p, err := parsePattern(hist)
+		if err != nil {
+			return fmt.Errorf("cannot aggregate %s: %v", path, err)
+		}
+		if p != nil {
+			s.histPatterns = append(s.histPatterns, p[0].re)
+		} else {
+			s.hist[unquote(hist)] = 0
+		}
+		s.stats["hist"] = true
+	case isStat(stat):
+		s.stats[stat] = true
+	default:
+		// A glob or regexp matching the statistics, like the field path
+		// itself (eg: latency.*).
+		p, err := parsePattern(stat)
+		if err != nil {
+			return fmt.Errorf("cannot aggregate %s: %v", path, err)
+		}
+		if p == nil {
+			return fmt.Errorf("cannot aggregate %s: invalid statistic %s", path, stat)
+		}
+		for _, stat := range aggregateStats {
+			s.stats[stat] = true
+		}
+	}
+	return nil
+}
+
+// isStat returns true if stat is the name of a numeric statistic.
+func isStat(stat string) bool {
+	switch stat {
+	case "count", "sum", "avg", "min", "max":
+		return true
+	}
+	_, ok := parsePercentile(stat)
+	return ok
+}
+
+// read adds the events read from r to the current bucket. Lines which are
+// not JSON objects are ignored, whatever their length.
+func (e *eventSource) read(r io.Reader) error {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			e.addEvent(line)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// addEvent adds the event of line to the current bucket, unless line is not a
+// JSON object.
+func (e *eventSource) addEvent(line []byte) {
+	jq, err := gojq.NewStringQuery(string(line))
+	if err != nil {
+		return
+	}
+	if _, ok := jq.Data.(map[string]interface{}); !ok {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.count++
+	for _, s := range e.series {
+		v, err := query(jq, s.path)
+		if err != nil {
+			continue
+		}
+		if n, ok := v.(float64); ok {
+			s.values = append(s.values, n)
+		}
+		if !s.stats["hist"] {
+			continue
+		}
+		var value string
+		switch v := v.(type) {
+		case float64:
+			value = strconv.FormatFloat(v, 'f', -1, 64)
+		case string:
+			value = v
+		case bool:
+			value = strconv.FormatBool(v)
+		default:
+			continue
+		}
+		if s.counts(value) {
+			s.hist[value]++
+		}
+	}
+}
+
+// counts returns true if the events having value are counted in the
+// histogram of s.
+func (s *eventSeries) counts(value string) bool {
+	if _, found := s.hist[value]; found {
+		return true
+	}
+	for _, re := range s.histPatterns {
+		if re.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// run sends the aggregate of the events received every interval, until the
+// input is exhausted or the source closed.
+func (e *eventSource) run(interval time.Duration, eof chan error) {
+	defer close(e.c)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if !e.send(res{jq: e.flush()}) {
+				return
+			}
+		case err := <-eof:
+			// Send the last partial bucket.
+			if err == nil {
+				e.send(res{jq: e.flush()})
+			} else {
+				e.send(res{err: err})
+			}
+			return
+		case <-e.done:
+			return
+		}
+	}
+}
+
+func (e *eventSource) send(r res) bool {
+	select {
+	case e.c <- r:
+		return true
+	case <-e.done:
+		return false
+	}
+}
+
+// flush returns the aggregate of the current bucket and starts a new one.
+func (e *eventSource) flush() *gojq.JQ {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	agg := map[string]interface{}{}
+	setPath(agg, []string{"count"}, e.count)
+	e.count = 0
+	for _, s := range e.series {
+		for stat := range s.stats {
+			if stat == "hist" {
+				for value, count := range s.hist {
+					setPath(agg, append(s.keys[:len(s.keys):len(s.keys)], "hist", value), count)
+					s.hist[value] = 0
+				}
+				continue
+			}
+			setPath(agg, append(s.keys[:len(s.keys):len(s.keys)], stat), aggregateStat(stat, s.values))
+		}
+		s.values = s.values[:0]
+	}
+	return gojq.NewQuery(agg)
+}
+
+// aggregateStat computes the statistic stat of values. Statistics other than
+// the count and the sum are missing when there is no value.
+func aggregateStat(stat string, values []float64) float64 {
+	switch stat {
+	case "count":
+		return float64(len(values))
+	case "sum":
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+		return sum
+	}
+	if len(values) == 0 {
+		return math.NaN()
+	}
+	p, ok := parsePercentile(stat)
+	if ok {
+		stat = "p"
+	}
+	return statistic(stat, p, values)
+}
+
+// setPath sets the value at path in m, creating the intermediate objects.
+func setPath(m map[string]interface{}, path []string, v interface{}) {
+	for _, k := range path[:len(path)-1] {
+		child, ok := m[k].(map[string]interface{})
+		if !ok {
+			child = map[string]interface{}{}
+			m[k] = child
+		}
+		m = child
+	}
+	m[path[len(path)-1]] = v
+}
+
+func (e *eventSource) Get() (*gojq.JQ, error) {
+	r, ok := <-e.c
+	if !ok {
+		return nil, nil
+	}
+	return r.jq, r.err
+}
+
+func (e *eventSource) Close() error {
+	e.closer.Do(func() {
+		close(e.done)
+	})
+	return nil
+}
@@ -0,0 +1,153 @@
+package data
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/elgs/gojq"
+)
+
+// csvRows converts the rows of a CSV or TSV table to samples keyed by the
+// names of the columns given by the first row.
+type csvRows struct {
+	header []string
+}
+
+// sample returns the sample of row, or nil if row is the header. Numeric
+// cells are numbers and other cells strings, including the ones spelling
+// non-finite numbers like inf or NaN, while empty cells are left out.
+func (c *csvRows) sample(row []string) *gojq.JQ {
+	for i := range row {
+		row[i] = strings.TrimSpace(row[i])
+	}
+	if c.header == nil {
+		c.header = append([]string(nil), row...)
+		return nil
+	}
+	if isHeader := len(row) == len(c.header); isHeader {
+		// Some tools repeat the header from time to time.
+		for i := range row {
+			if row[i] != c.header[i] {
+				isHeader = false
+				break
+			}
+		}
+		if isHeader {
+			return nil
+		}
+	}
+	m := make(map[string]interface{}, len(row))
+	for i, v := range row {
+		if i >= len(c.header) || v == "" {
+			continue
+		}
+		if n, err := strconv.ParseFloat(v, 64); err == nil && !math.IsNaN(n) && !math.IsInf(n, 0) {
+			m[c.header[i]] = n
+		} else {
+			m[c.header[i]] = v
+		}
+	}
+	return gojq.NewQuery(m)
+}
+
+func newCSVReader(r io.Reader, comma rune) *csv.Reader {
+	cr := csv.NewReader(r)
+	cr.Comma = comma
+	cr.Comment = '#'
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	return cr
+}
+
+type csvSource struct {
+	f    *os.File
+	r    *csv.Reader
+	rows csvRows
+}
+
+// FromStdinCSV reads data from stdin as a table of values separated by comma,
+// such as ',' for CSV or '\t' for TSV. The first row holds the names of the
+// columns, which are the paths of the fields.
+func FromStdinCSV(comma rune, size int) *Points {
+	f := openStdin()
+	return &Points{
+		Size:   size,
+		Source: &csvSource{f: f, r: newCSVReader(f, comma)},
+	}
+}
+
+func (s *csvSource) Get() (*gojq.JQ, error) {
+	for {
+		row, err := s.r.Read()
+		if err == io.EOF || errors.Is(err, os.ErrClosed) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if jq := s.rows.sample(row); jq != nil {
+			return jq, nil
+		}
+	}
+}
+
+func (s *csvSource) Close() error {
+	return closeStdin(s.f)
+}
+
+// csvLines returns a function decoding the lines of a table of values
+// separated by comma, the first line being the header. Lines ending in a
+// quoted cell are held until the line closing the cell, so that the cells
+// holding newlines are decoded as in FromStdinCSV.
+func csvLines(comma rune) func(line []byte) *gojq.JQ {
+	rows := &csvRows{}
+	var record []byte
+	return func(line []byte) *gojq.JQ {
+		record = append(record, line...)
+		if inQuotes(record, comma) {
+			return nil
+		}
+		defer func() {
+			record = record[:0]
+		}()
+		if len(bytes.TrimSpace(record)) == 0 {
+			return nil
+		}
+		row, err := newCSVReader(bytes.NewReader(record), comma).Read()
+		if err != nil {
+			return nil
+		}
+		return rows.sample(row)
+	}
+}
+
+// inQuotes returns true if record ends within a quoted cell. Like the
+// reader of newCSVReader, quotes start a quoted cell only at the beginning of
+// a cell and are doubled within it, and comment lines have no cells.
+func inQuotes(record []byte, comma rune) bool {
+	if len(record) > 0 && record[0] == '#' {
+		return false
+	}
+	quoted, start := false, true
+	for i := 0; i < len(record); i++ {
+		c := record[i]
+		switch {
+		case quoted && c == '"':
+			if i+1 < len(record) && record[i+1] == '"' {
+				i++
+			} else {
+				quoted = false
+			}
+		case !quoted && start && c == '"':
+			quoted = true
+		}
+		start = !quoted && (rune(c) == comma || c == '\n')
+	}
+	return quoted
+}
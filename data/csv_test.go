@@ -0,0 +1,143 @@
+package data
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCSVRows(t *testing.T) {
+	rows := &csvRows{}
+	tests := []struct {
+		row  []string
+		want map[string]interface{}
+	}{
+		{row: []string{"time", " load ", "state", "note"}},
+		{
+			row:  []string{"1", " 0.5", "up", ""},
+			want: map[string]interface{}{"time": 1.0, "load": 0.5, "state": "up"},
+		},
+		{
+			// Non-finite numbers are strings.
+			row:  []string{"2", "inf", "NaN", "Infinity"},
+			want: map[string]interface{}{"time": 2.0, "load": "inf", "state": "NaN", "note": "Infinity"},
+		},
+		{
+			// Extra cells have no column.
+			row:  []string{"3", "1e3", "down", "x", "extra"},
+			want: map[string]interface{}{"time": 3.0, "load": 1000.0, "state": "down", "note": "x"},
+		},
+		// Repeated header.
+		{row: []string{"time", "load", "state", "note"}},
+	}
+	for i, tt := range tests {
+		jq := rows.sample(tt.row)
+		if tt.want == nil {
+			if jq != nil {
+				t.Errorf("row %d: got %v, want no sample", i, jq.Data)
+			}
+			continue
+		}
+		if jq == nil {
+			t.Errorf("row %d: got no sample, want %v", i, tt.want)
+			continue
+		}
+		if !reflect.DeepEqual(jq.Data, tt.want) {
+			t.Errorf("row %d: got %v, want %v", i, jq.Data, tt.want)
+		}
+	}
+}
+
+func TestCSVLines(t *testing.T) {
+	tests := []struct {
+		name  string
+		comma rune
+		lines []string
+		want  []map[string]interface{}
+	}{
+		{
+			name:  "csv",
+			comma: ',',
+			lines: []string{"a,b\n", "1,2\n", "\n", "# comment\n", "3,x\n"},
+			want: []map[string]interface{}{
+				{"a": 1.0, "b": 2.0},
+				{"a": 3.0, "b": "x"},
+			},
+		},
+		{
+			name:  "tsv",
+			comma: '\t',
+			lines: []string{"a\tb\n", "1\t2,5\n"},
+			want: []map[string]interface{}{
+				{"a": 1.0, "b": "2,5"},
+			},
+		},
+		{
+			name:  "quotes",
+			comma: ',',
+			lines: []string{"a,b\n", `1,"x, ""y"""` + "\n", `2,5" screen` + "\n", `3,"a ""quoted""` + "\n", `line"` + "\n"},
+			want: []map[string]interface{}{
+				{"a": 1.0, "b": `x, "y"`},
+				{"a": 2.0, "b": `5" screen`},
+				{"a": 3.0, "b": "a \"quoted\"\nline"},
+			},
+		},
+		{
+			name:  "newlines",
+			comma: ',',
+			lines: []string{"a,b\n", "1,\"first\n", "\n", "last\"\n", "# \"not a quote\n", "2,\"x\ny\"\n", "3,z\n"},
+			want: []map[string]interface{}{
+				{"a": 1.0, "b": "first\n\nlast"},
+				{"a": 2.0, "b": "x\ny"},
+				{"a": 3.0, "b": "z"},
+			},
+		},
+		{
+			name:  "multi-line header",
+			comma: ',',
+			lines: []string{"\"a\n", "b\",c\n", "1,2\n"},
+			want: []map[string]interface{}{
+				{"a\nb": 1.0, "c": 2.0},
+			},
+		},
+	}
+	for _, tt := range tests {
+		decode := csvLines(tt.comma)
+		var got []map[string]interface{}
+		for _, line := range tt.lines {
+			if jq := decode([]byte(line)); jq != nil {
+				got = append(got, jq.Data.(map[string]interface{}))
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestInQuotes(t *testing.T) {
+	tests := []struct {
+		record string
+		want   bool
+	}{
+		{"a,b\n", false},
+		{`a,"b` + "\n", true},
+		{`a,"b"` + "\n", false},
+		{`a,"b""` + "\n", true},
+		{`a,"b"""` + "\n", false},
+		{`a,b"c` + "\n", false},
+		{`"a,b` + "\n", true},
+		{`"a` + "\n" + `b",c` + "\n", false},
+		{`# "comment` + "\n", false},
+		{"a\t\"b\n", true},
+	}
+	for _, tt := range tests {
+		comma := ','
+		if strings.Contains(tt.record, "\t") {
+			comma = '\t'
+		}
+		if got := inQuotes([]byte(tt.record), comma); got != tt.want {
+			t.Errorf("inQuotes(%q) = %v, want %v", tt.record, got, tt.want)
+		}
+	}
+}
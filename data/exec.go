@@ -0,0 +1,130 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/elgs/gojq"
+)
+
+type execSource struct {
+	c       chan res
+	ctx     context.Context
+	cancel  context.CancelFunc
+	command string
+	timeout time.Duration
+}
+
+// FromExec runs command with the shell every interval and parses its output
+// as a JSON sample, keeping size points. A run lasting more than timeout
+// (interval when zero) is killed. Failed runs are recorded as gaps and the
+// command keeps being run.
+func FromExec(command string, interval, timeout time.Duration, size int) *Points {
+	if timeout == 0 {
+		timeout = interval
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	e := execSource{
+		c:       make(chan res),
+		ctx:     ctx,
+		cancel:  cancel,
+		command: command,
+		timeout: timeout,
+	}
+	go e.run(interval)
+	return &Points{
+		Size:   size,
+		Source: e,
+	}
+}
+
+func (e execSource) run(interval time.Duration) {
+	defer close(e.c)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		jq, err := e.exec()
+		if err != nil {
+			err = &MissedError{Err: err}
+		}
+		select {
+		case e.c <- res{jq: jq, err: err}:
+		case <-e.ctx.Done():
+			return
+		}
+		select {
+		case <-t.C:
+		case <-e.ctx.Done():
+			return
+		}
+	}
+}
+
+// exec runs the command once and parses its output. On timeout, the
+// processes started by the command are killed too, as they would otherwise
+// keep its output open.
+func (e execSource) exec() (*gojq.JQ, error) {
+	ctx, cancel := context.WithTimeout(e.ctx, e.timeout)
+	defer cancel()
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", e.command)
+	} else {
+		cmd = exec.Command("sh", "-c", e.command)
+	}
+	setGroup(cmd)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		killGroup(cmd)
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			// A process which left the group still holds the output.
+		}
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("command timed out after %v", e.timeout)
+		}
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			// The first line of stderr usually tells what went wrong.
+			if msg, _, _ := strings.Cut(strings.TrimSpace(stderr.String()), "\n"); msg != "" {
+				return nil, fmt.Errorf("%v: %s", err, msg)
+			}
+		}
+		return nil, err
+	}
+	return parseJSON(stdout.Bytes())
+}
+
+func (e execSource) Get() (*gojq.JQ, error) {
+	res, ok := <-e.c
+	if !ok {
+		return nil, nil
+	}
+	return res.jq, res.err
+}
+
+func (e execSource) Close() error {
+	e.cancel()
+	return nil
+}
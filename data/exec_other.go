@@ -0,0 +1,22 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package data
+
+import (
+	"os/exec"
+	"runtime"
+	"strconv"
+)
+
+func setGroup(cmd *exec.Cmd) {}
+
+// killGroup kills cmd and, on Windows, the processes it started.
+func killGroup(cmd *exec.Cmd) error {
+	if runtime.GOOS == "windows" {
+		if err := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run(); err == nil {
+			return nil
+		}
+	}
+	return cmd.Process.Kill()
+}
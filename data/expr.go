@@ -0,0 +1,360 @@
+package data
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/elgs/gojq"
+)
+
+// expr is an arithmetic expression over field paths, such as
+// memstats.HeapInuse/memstats.HeapSys*100.
+type expr interface {
+	eval(jq *gojq.JQ) (float64, error)
+}
+
+type number float64
+
+func (n number) eval(*gojq.JQ) (float64, error) {
+	return float64(n), nil
+}
+
+type operand string
+
+func (o operand) eval(jq *gojq.JQ) (float64, error) {
+	return queryNumber(jq, unquote(string(o)))
+}
+
+// reduction reduces the numbers of an array to a single value: their count,
+// sum, average, minimum, maximum or a percentile. A number is reduced as an
+// array of one item.
+type reduction struct {
+	stat string
+	path string
+}
+
+func (r reduction) eval(jq *gojq.JQ) (float64, error) {
+	path := unquote(r.path)
+	v, err := query(jq, path)
+	if err != nil {
+		return 0, fmt.Errorf("cannot get %s: %v", path, err)
+	}
+	var values []float64
+	switch v := v.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if n, ok := item.(float64); ok {
+				values = append(values, n)
+			}
+		}
+	case float64:
+		values = append(values, v)
+	default:
+		return 0, fmt.Errorf("invalid type %s: %T", path, v)
+	}
+	return aggregateStat(r.stat, values), nil
+}
+
+type negation struct {
+	x expr
+}
+
+func (n negation) eval(jq *gojq.JQ) (float64, error) {
+	v, err := n.x.eval(jq)
+	return -v, err
+}
+
+type binary struct {
+	op   byte
+	x, y expr
+}
+
+func (b binary) eval(jq *gojq.JQ) (float64, error) {
+	x, err := b.x.eval(jq)
+	if err != nil {
+		return 0, err
+	}
+	y, err := b.y.eval(jq)
+	if err != nil {
+		return 0, err
+	}
+	switch b.op {
+	case '+':
+		return x + y, nil
+	case '-':
+		return x - y, nil
+	case '*':
+		return x * y, nil
+	}
+	if y == 0 {
+		// Plotted as a gap rather than an infinity.
+		return math.NaN(), nil
+	}
+	return x / y, nil
+}
+
+// exprPaths returns the paths x refers to.
+func exprPaths(x expr) []string {
+	switch x := x.(type) {
+	case operand:
+		return []string{unquote(string(x))}
+	case reduction:
+		return []string{unquote(x.path)}
+	case negation:
+		return exprPaths(x.x)
+	case binary:
+		return append(exprPaths(x.x), exprPaths(x.y)...)
+	}
+	return nil
+}
+
+// sourcePath returns the first path of x prefixed with a source name (eg:
+// canary@requests), or an empty string if there is none. Such paths cannot be
+// read as an expression is evaluated over the sample of a single source.
+func sourcePath(x expr) string {
+	var path string
+	switch x := x.(type) {
+	case operand:
+		path = string(x)
+	case reduction:
+		path = x.path
+	case negation:
+		return sourcePath(x.x)
+	case binary:
+		if path = sourcePath(x.x); path == "" {
+			path = sourcePath(x.y)
+		}
+		return path
+	}
+	if idx := strings.IndexByte(path, '@'); idx > 0 && isSourceName(path[:idx]) {
+		return path
+	}
+	return ""
+}
+
+// isExpr returns true if s is an arithmetic expression rather than a path:
+// an expression between parentheses (eg: (errors/requests*100)) or the
+// reduction of an array (eg: p99(memstats.PauseNs)).
+func isExpr(s string) bool {
+	i := strings.IndexByte(s, '(')
+	if i != 0 && (i == -1 || !isStat(s[:i])) {
+		return false
+	}
+	return closingParen(s, i) == len(s)-1
+}
+
+// closingParen returns the index of the parenthesis closing the one at open
+// in s, out of quotes, or -1 if missing.
+func closingParen(s string, open int) int {
+	var quote byte
+	depth := 0
+	for i := open; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// looksLikeExpr returns true if the path s, which is not an expression, holds
+// parentheses, spaces or a slash within a path segment, out of quotes and
+// regexp segments. Such a path was likely meant as an expression.
+func looksLikeExpr(s string) bool {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '/' && (i == 0 || s[i-1] == '.'):
+			// A regexp segment.
+			quote = c
+		case c == '/' || c == '(' || c == ')' || c == ' ':
+			return true
+		}
+	}
+	return false
+}
+
+// tokenize splits the expression s into operands and operators. Keys holding
+// operators, such as sub-field, must be quoted.
+func tokenize(s string) []string {
+	var tokens []string
+	var tok strings.Builder
+	flush := func() {
+		if tok.Len() > 0 {
+			tokens = append(tokens, tok.String())
+			tok.Reset()
+		}
+	}
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && i+1 < len(s) {
+				tok.WriteByte(c)
+				i++
+				c = s[i]
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ' ' || c == '\t':
+			flush()
+			continue
+		case strings.IndexByte("()+*/", c) != -1, c == '-' && !isExponent(tok.String()):
+			flush()
+			tokens = append(tokens, string(c))
+			continue
+		}
+		tok.WriteByte(c)
+	}
+	flush()
+	return tokens
+}
+
+// isExponent returns true if tok is a number followed by the exponent marker,
+// as in 1e-3.
+func isExponent(tok string) bool {
+	n := len(tok) - 1
+	if n < 1 || tok[n] != 'e' && tok[n] != 'E' {
+		return false
+	}
+	_, err := strconv.ParseFloat(tok[:n], 64)
+	return err == nil
+}
+
+// parseExpr parses the arithmetic expression s. Operators are +, -, * and /
+// with the usual precedence, and parentheses. Arrays are reduced to a number
+// with count, sum, avg, min, max or a percentile (eg: p99(path)).
+func parseExpr(s string) (expr, error) {
+	p := &exprParser{tokens: tokenize(s)}
+	x, err := p.expr()
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %s: %v", s, err)
+	}
+	if len(p.tokens) > 0 {
+		return nil, fmt.Errorf("invalid expression %s: unexpected %s", s, p.tokens[0])
+	}
+	return x, nil
+}
+
+type exprParser struct {
+	tokens []string
+}
+
+func (p *exprParser) next() string {
+	if len(p.tokens) == 0 {
+		return ""
+	}
+	t := p.tokens[0]
+	p.tokens = p.tokens[1:]
+	return t
+}
+
+func (p *exprParser) peek() string {
+	if len(p.tokens) == 0 {
+		return ""
+	}
+	return p.tokens[0]
+}
+
+// expr parses a sum of terms.
+func (p *exprParser) expr() (expr, error) {
+	x, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for t := p.peek(); t == "+" || t == "-"; t = p.peek() {
+		p.next()
+		y, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		x = binary{op: t[0], x: x, y: y}
+	}
+	return x, nil
+}
+
+// term parses a product of factors.
+func (p *exprParser) term() (expr, error) {
+	x, err := p.factor()
+	if err != nil {
+		return nil, err
+	}
+	for t := p.peek(); t == "*" || t == "/"; t = p.peek() {
+		p.next()
+		y, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		x = binary{op: t[0], x: x, y: y}
+	}
+	return x, nil
+}
+
+// factor parses a number, a path, a reduction, a negation or an expression in
+// parentheses.
+func (p *exprParser) factor() (expr, error) {
+	switch t := p.next(); t {
+	case "":
+		return nil, fmt.Errorf("unexpected end")
+	case "-":
+		x, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		return negation{x}, nil
+	case "(":
+		x, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		return x, nil
+	case ")", "+", "*", "/":
+		return nil, fmt.Errorf("unexpected %s", t)
+	default:
+		if n, err := strconv.ParseFloat(t, 64); err == nil {
+			return number(n), nil
+		}
+		if p.peek() == "(" && isStat(t) {
+			p.next()
+			path := p.next()
+			if path == "" || strings.Contains("()+-*/", path) {
+				return nil, fmt.Errorf("invalid argument of %s", t)
+			}
+			if p.next() != ")" {
+				return nil, fmt.Errorf("missing )")
+			}
+			return reduction{stat: t, path: path}, nil
+		}
+		return operand(t), nil
+	}
+}
@@ -0,0 +1,176 @@
+package data
+
+import (
+	"math"
+	"testing"
+
+	"github.com/elgs/gojq"
+)
+
+func TestParseExpr(t *testing.T) {
+	jq, err := gojq.NewStringQuery(`{"a": 2, "b": 3, "c": 4, "a-b": 10, "a*b": 7, "x": {"y": 5}, "arr": [1, 2, 3, 4]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		expr string
+		want float64
+	}{
+		// Precedence and associativity.
+		{"(a+b*c)", 14},
+		{"((a+b)*c)", 20},
+		{"(a*b+c)", 10},
+		{"(c-b-a)", -1},
+		{"(c-(b-a))", 3},
+		{"(c/a/a)", 1},
+		{"(c/(a/a))", 4},
+		{"(a+b-c+a)", 3},
+		// Unary minus.
+		{"(-a+b)", 1},
+		{"(-(a+b))", -5},
+		{"(a*-b)", -6},
+		{"(a--b)", 5},
+		{"(a-b)", -1},
+		{"(a - b)", -1},
+		// Numbers.
+		{"(1e-3*1000)", 1},
+		{"(2E-1+a)", 2.2},
+		{"(1e3-a)", 998},
+		{"(a*100)", 200},
+		// Quoted keys holding operators.
+		{"('a-b'+1)", 11},
+		{`("a-b"/a)`, 5},
+		{`('a*b'-"a-b")`, -3},
+		// Nested paths and reductions.
+		{"(x.y*2)", 10},
+		{"(sum(arr)/count(arr))", 2.5},
+		{"p50(arr)", 2.5},
+		{"max(arr)", 4},
+		{"(min(arr)+avg(a))", 3},
+	}
+	for _, tt := range tests {
+		x, err := parseExpr(tt.expr)
+		if err != nil {
+			t.Errorf("parseExpr(%s): %v", tt.expr, err)
+			continue
+		}
+		got, err := x.eval(jq)
+		if err != nil {
+			t.Errorf("eval(%s): %v", tt.expr, err)
+			continue
+		}
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("eval(%s) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParseExprErrors(t *testing.T) {
+	for _, s := range []string{
+		"()",
+		"(a+)",
+		"(a",
+		"(a))",
+		"(*a)",
+		"(a b)",
+		"(a+*b)",
+		"(p99(a+b))",
+		"(p99())",
+	} {
+		if _, err := parseExpr(s); err == nil {
+			t.Errorf("parseExpr(%s): expected an error", s)
+		}
+	}
+}
+
+func TestExprEval(t *testing.T) {
+	jq, err := gojq.NewStringQuery(`{"a": 2, "zero": 0, "s": "x"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		expr    string
+		wantNaN bool
+		wantErr bool
+	}{
+		{expr: "(a/zero)", wantNaN: true},
+		{expr: "(a+missing)", wantErr: true},
+		{expr: "(a+s)", wantErr: true},
+		{expr: "sum(missing)", wantErr: true},
+	}
+	for _, tt := range tests {
+		x, err := parseExpr(tt.expr)
+		if err != nil {
+			t.Errorf("parseExpr(%s): %v", tt.expr, err)
+			continue
+		}
+		got, err := x.eval(jq)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("eval(%s): error %v, want error %v", tt.expr, err, tt.wantErr)
+		}
+		if tt.wantNaN && !math.IsNaN(got) {
+			t.Errorf("eval(%s) = %v, want NaN", tt.expr, got)
+		}
+	}
+}
+
+func TestIsExpr(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{"(a/b)", true},
+		{"((a+b)*c)", true},
+		{"p99(memstats.PauseNs)", true},
+		{"count(a)", true},
+		{"(a)/(b)", false},
+		{"p99(a)+1", false},
+		{"foo(a)", false},
+		{"a-b", false},
+		{"a.b", false},
+		{"'(a)'", false},
+		{"('(')", true},
+	}
+	for _, tt := range tests {
+		if got := isExpr(tt.s); got != tt.want {
+			t.Errorf("isExpr(%s) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestParseSpecExpr(t *testing.T) {
+	tests := []struct {
+		spec       string
+		wantSource string
+		wantErr    bool
+	}{
+		{spec: "(errors/requests)"},
+		{spec: "canary@(errors/requests)", wantSource: "canary"},
+		{spec: "canary@p99(memstats.PauseNs)", wantSource: "canary"},
+		{spec: "('user@host'+1)"},
+		{spec: "(canary@errors/requests)", wantErr: true},
+		{spec: "canary@(errors/stable@requests)", wantErr: true},
+		{spec: "p99(canary@memstats.PauseNs)", wantErr: true},
+		{spec: "(-canary@a)", wantErr: true},
+		{spec: "errors/requests", wantErr: true},
+		{spec: "a b", wantErr: true},
+		{spec: "(a+b", wantErr: true},
+	}
+	for _, tt := range tests {
+		specs, err := ParseSpec([]string{tt.spec})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSpec(%s): error %v, want error %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		f := specs[0].Fields[0]
+		if f.expr == nil {
+			t.Errorf("ParseSpec(%s): not parsed as an expression", tt.spec)
+		}
+		if f.Source != tt.wantSource {
+			t.Errorf("ParseSpec(%s): source %q, want %q", tt.spec, f.Source, tt.wantSource)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/elgs/gojq"
+)
+
+// followInterval is the delay between two checks for new lines once the end
+// of a followed file is reached.
+const followInterval = 250 * time.Millisecond
+
+// fileSource follows a file of samples, one per line, like tail -F.
+type fileSource struct {
+	c      chan res
+	done   chan struct{}
+	closer sync.Once
+	path   string
+	// decoder returns a function decoding the lines of the file from its
+	// start, returning nil for lines holding no sample.
+	decoder func() func(line []byte) *gojq.JQ
+}
+
+// FromFile follows the file at path as one JSON object per line and keep size
+// points. Only the lines appended after the start are read, unless backfill is
+// set in which case the existing lines are read first.
+//
+// The file is reopened when it is replaced, such as when a log rotator renames
+// it, and read from its start when truncated. Lines which are not JSON objects
+// are ignored.
+func FromFile(path string, backfill bool, size int) *Points {
+	return followFile(path, backfill, size, func() func([]byte) *gojq.JQ {
+		return jsonLine
+	})
+}
+
+// FromFileCSV follows the file at path like FromFile, reading it as a table of
+// values separated by comma like FromStdinCSV. The header is read from the
+// first line of the file, even when only the appended lines are.
+func FromFileCSV(path string, comma rune, backfill bool, size int) *Points {
+	return followFile(path, backfill, size, func() func([]byte) *gojq.JQ {
+		return csvLines(comma)
+	})
+}
+
+func followFile(path string, backfill bool, size int, decoder func() func([]byte) *gojq.JQ) *Points {
+	s := &fileSource{
+		c:       make(chan res),
+		done:    make(chan struct{}),
+		path:    path,
+		decoder: decoder,
+	}
+	go s.run(backfill)
+	return &Points{
+		Size:   size,
+		Source: s,
+	}
+}
+
+func (s *fileSource) run(backfill bool) {
+	defer close(s.c)
+	var f *os.File
+	defer func() {
+		if f != nil {
+			f.Close()
+		}
+	}()
+	var r *bufio.Reader
+	var decode func([]byte) *gojq.JQ
+	var offset int64
+	var line []byte // line read up to the end of the file, not terminated yet
+	start := true
+	for {
+		if f == nil {
+			var err error
+			if f, err = os.Open(s.path); err != nil {
+				f = nil
+			} else {
+				offset = 0
+				decode = s.decoder()
+				if start && !backfill {
+					// The first line may be a header needed to decode the
+					// following ones.
+					if first, err := bufio.NewReader(f).ReadBytes('\n'); err == nil {
+						decode(first)
+					}
+					offset, _ = f.Seek(0, io.SeekEnd)
+				}
+				r = bufio.NewReader(f)
+				line = line[:0]
+			}
+			// A file created after the start is read from its beginning.
+			start = false
+		}
+		if f != nil {
+			for {
+				b, err := r.ReadBytes('\n')
+				offset += int64(len(b))
+				line = append(line, b...)
+				if err != nil {
+					break
+				}
+				if !s.send(decode(line)) {
+					return
+				}
+				line = line[:0]
+			}
+			// The end of the file is reached, check whether it was rotated
+			// or truncated. While the file is missing, it is likely being
+			// rotated and the current one is still followed.
+			if cur, err := os.Stat(s.path); err == nil {
+				if fi, err := f.Stat(); err == nil && !os.SameFile(cur, fi) {
+					f.Close()
+					f = nil
+					continue
+				}
+				if cur.Size() < offset {
+					if _, err := f.Seek(0, io.SeekStart); err == nil {
+						r.Reset(f)
+						decode = s.decoder()
+						offset = 0
+						line = line[:0]
+						continue
+					}
+				}
+			}
+		}
+		select {
+		case <-time.After(followInterval):
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// jsonLine returns the JSON object of line, or nil for lines of another kind.
+func jsonLine(line []byte) *gojq.JQ {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return nil
+	}
+	jq, err := gojq.NewStringQuery(string(line))
+	if err != nil {
+		return nil
+	}
+	if _, ok := jq.Data.(map[string]interface{}); !ok {
+		return nil
+	}
+	return jq
+}
+
+// send sends jq unless nil. It returns false if the source is closed.
+func (s *fileSource) send(jq *gojq.JQ) bool {
+	if jq == nil {
+		return true
+	}
+	select {
+	case s.c <- res{jq: jq}:
+		return true
+	case <-s.done:
+		return false
+	}
+}
+
+func (s *fileSource) Get() (*gojq.JQ, error) {
+	r, ok := <-s.c
+	if !ok {
+		return nil, nil
+	}
+	return r.jq, r.err
+}
+
+func (s *fileSource) Close() error {
+	s.closer.Do(func() {
+		close(s.done)
+	})
+	return nil
+}
@@ -1,16 +1,140 @@
 package data
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/elgs/gojq"
 )
 
+// HTTPOptions configures how HTTP sources fetch their data.
+type HTTPOptions struct {
+	// Interval is the duration between fetches.
+	Interval time.Duration
+	// Retries is the number of times a failed fetch is retried before the
+	// sample is recorded as missing.
+	Retries int
+	// Backoff is the delay before the first retry. It is doubled after each
+	// attempt, but retries never spill over the next fetch.
+	Backoff time.Duration
+	// Timeout is the maximum duration of a request, Interval when zero.
+	Timeout time.Duration
+	// Method is the method of the requests, GET by default or POST when Body
+	// is set.
+	Method string
+	// Body is sent with each request, as JSON unless a Content-Type header is
+	// set.
+	Body string
+	// Header holds the headers added to each request. A Host header
+	// overrides the host sent to the server.
+	Header http.Header
+	// BearerToken is sent in the Authorization header when set.
+	BearerToken string
+	// Username and Password are sent using basic authentication when
+	// Username is set.
+	Username string
+	Password string
+	// CertFile and KeyFile are the PEM encoded certificate and key presented
+	// to servers requiring a client certificate. The key is read from
+	// CertFile when KeyFile is empty.
+	CertFile string
+	KeyFile  string
+	// CAFile is a PEM bundle of the certificate authorities trusted to verify
+	// servers instead of the system ones.
+	CAFile string
+}
+
+// client returns the HTTP client configured by o.
+func (o HTTPOptions) client() (*http.Client, error) {
+	timeout := o.Timeout
+	if timeout == 0 {
+		timeout = o.Interval
+	}
+	if o.KeyFile != "" && o.CertFile == "" {
+		return nil, fmt.Errorf("cannot load client certificate: key %s given without certificate", o.KeyFile)
+	}
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	if o.CertFile != "" || o.CAFile != "" {
+		cfg := &tls.Config{}
+		if o.CertFile != "" {
+			keyFile := o.KeyFile
+			if keyFile == "" {
+				keyFile = o.CertFile
+			}
+			cert, err := tls.LoadX509KeyPair(o.CertFile, keyFile)
+			if err != nil {
+				return nil, fmt.Errorf("cannot load client certificate: %v", err)
+			}
+			cfg.Certificates = []tls.Certificate{cert}
+		}
+		if o.CAFile != "" {
+			b, err := os.ReadFile(o.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("cannot load CA bundle: %v", err)
+			}
+			cfg.RootCAs = x509.NewCertPool()
+			if !cfg.RootCAs.AppendCertsFromPEM(b) {
+				return nil, fmt.Errorf("cannot load CA bundle: no certificate found in %s", o.CAFile)
+			}
+		}
+		tr.TLSClientConfig = cfg
+	}
+	return &http.Client{Transport: tr, Timeout: timeout}, nil
+}
+
+// request returns a request to url configured by o.
+func (o HTTPOptions) request(url string) (*http.Request, error) {
+	method := o.Method
+	var body io.Reader
+	if o.Body != "" {
+		body = strings.NewReader(o.Body)
+		if method == "" {
+			method = http.MethodPost
+		}
+	}
+	if method == "" {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, values := range o.Header {
+		if http.CanonicalHeaderKey(k) == "Host" {
+			// The Host header is taken from req.Host by net/http.
+			req.Host = values[len(values)-1]
+			continue
+		}
+		for _, v := range values {
+			req.Header.Add(k, v)
+		}
+	}
+	if o.Body != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if o.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+o.BearerToken)
+	}
+	if o.Username != "" {
+		req.SetBasicAuth(o.Username, o.Password)
+	}
+	return req, nil
+}
+
 type httpSource struct {
-	c    chan res
-	done chan struct{}
+	c      chan res
+	done   chan struct{}
+	closer *sync.Once
+	decode func([]byte) (*gojq.JQ, error)
+	opts   HTTPOptions
+	client *http.Client
 }
 
 type res struct {
@@ -18,21 +142,39 @@ type res struct {
 	err error
 }
 
-// FromHTTP fetch data points from url every interval and keep size points.
-func FromHTTP(url string, interval time.Duration, size int) *Points {
-	h := httpSource{
-		c:    make(chan res),
-		done: make(chan struct{}),
+// FromHTTP fetch data points from url every opts.Interval and keep size points.
+func FromHTTP(url string, opts HTTPOptions, size int) (*Points, error) {
+	h, err := newHTTPSource(url, parseJSON, opts)
+	if err != nil {
+		return nil, err
 	}
-	go h.run(url, interval)
 	return &Points{
 		Size:   size,
 		Source: h,
+	}, nil
+}
+
+// newHTTPSource starts fetching url every opts.Interval, decoding responses
+// with decode.
+func newHTTPSource(url string, decode func([]byte) (*gojq.JQ, error), opts HTTPOptions) (httpSource, error) {
+	client, err := opts.client()
+	if err != nil {
+		return httpSource{}, err
+	}
+	h := httpSource{
+		c:      make(chan res),
+		done:   make(chan struct{}),
+		closer: &sync.Once{},
+		decode: decode,
+		opts:   opts,
+		client: client,
 	}
+	go h.run(url)
+	return h, nil
 }
 
-func (h httpSource) run(url string, interval time.Duration) {
-	t := time.NewTicker(interval)
+func (h httpSource) run(url string) {
+	t := time.NewTicker(h.opts.Interval)
 	defer t.Stop()
 	h.fetch(url)
 	for {
@@ -46,20 +188,56 @@ func (h httpSource) run(url string, interval time.Duration) {
 	}
 }
 
+// fetch gets url, retrying on failure, and sends the result to the channel. A
+// fetch still failing after all retries is sent as a MissedError so the
+// source keeps being polled.
 func (h httpSource) fetch(url string) {
-	resp, err := http.Get(url)
+	deadline := time.Now().Add(h.opts.Interval)
+	backoff := h.opts.Backoff
+	jq, err := h.get(url)
+	for i := 0; err != nil && i < h.opts.Retries; i++ {
+		if time.Now().Add(backoff).After(deadline) {
+			break
+		}
+		select {
+		case <-time.After(backoff):
+		case <-h.done:
+			return
+		}
+		backoff *= 2
+		jq, err = h.get(url)
+	}
+	if err != nil {
+		err = &MissedError{Err: err}
+	}
+	select {
+	case h.c <- res{jq: jq, err: err}:
+	case <-h.done:
+	}
+}
+
+func (h httpSource) get(url string) (*gojq.JQ, error) {
+	req, err := h.opts.request(url)
 	if err != nil {
-		h.c <- res{err: err}
-		return
+		return nil, err
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
 	b, err := io.ReadAll(resp.Body)
 	if err != nil {
-		h.c <- res{err: err}
-		return
+		return nil, err
 	}
-	jq, err := gojq.NewStringQuery(string(b))
-	h.c <- res{jq: jq, err: err}
+	return h.decode(b)
+}
+
+func parseJSON(b []byte) (*gojq.JQ, error) {
+	return gojq.NewStringQuery(string(b))
 }
 
 func (h httpSource) Get() (*gojq.JQ, error) {
@@ -68,6 +246,8 @@ func (h httpSource) Get() (*gojq.JQ, error) {
 }
 
 func (h httpSource) Close() error {
-	close(h.done)
+	h.closer.Do(func() {
+		close(h.done)
+	})
 	return nil
 }
@@ -0,0 +1,154 @@
+package data
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// pattern is a field path with glob (eg: requests.*.count) or regexp
+// (eg: requests./^api_/.count) segments, matching any number of fields.
+type pattern []segment
+
+// segment is a path segment matching either the key verbatim or the keys
+// matched by re.
+type segment struct {
+	key string
+	re  *regexp.Regexp
+}
+
+// split splits s on sep, except in quotes, regexp path segments and
+// parentheses.
+func split(s string, sep byte) []string {
+	var parts []string
+	var quote byte
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '/' && (i == 0 || strings.IndexByte(".+:,", s[i-1]) != -1):
+			// A regexp segment.
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == sep && depth <= 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// parsePattern returns the pattern of path, or nil if path has no glob or
+// regexp segment.
+func parsePattern(path string) (pattern, error) {
+	parts := split(path, '.')
+	p := make(pattern, 0, len(parts))
+	isPattern := false
+	for _, part := range parts {
+		s := segment{key: unquote(part)}
+		switch {
+		case len(part) >= 2 && part[0] == '/' && part[len(part)-1] == '/':
+			re, err := regexp.Compile(part[1 : len(part)-1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid path %s: %v", path, err)
+			}
+			s.re = re
+		case s.key == part:
+			s.re = globRegexp(part)
+		}
+		isPattern = isPattern || s.re != nil
+		p = append(p, s)
+	}
+	if !isPattern {
+		return nil, nil
+	}
+	return p, nil
+}
+
+// globRegexp returns the regexp matching the glob pattern, or nil if it has
+// no * wildcard outside of quotes. Other characters, such as the ? found in
+// URLs, are matched verbatim.
+func globRegexp(glob string) *regexp.Regexp {
+	var re strings.Builder
+	var quote rune
+	wildcard := false
+	for _, c := range glob {
+		switch {
+		case quote == 0 && c == '*':
+			wildcard = true
+			re.WriteString(".*")
+			continue
+		case c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		}
+		re.WriteString(regexp.QuoteMeta(string(c)))
+	}
+	if !wildcard {
+		return nil
+	}
+	return regexp.MustCompile("^" + re.String() + "$")
+}
+
+// unquote removes the quotes around a path segment, if any.
+func unquote(part string) string {
+	if len(part) >= 2 && (part[0] == '"' || part[0] == '\'') && part[len(part)-1] == part[0] {
+		return part[1 : len(part)-1]
+	}
+	return part
+}
+
+// match calls fn with the path and value of each field of v matched by p,
+// sorted by path.
+func (p pattern) match(v interface{}, fn func(path string, v interface{})) {
+	p.walk(v, nil, fn)
+}
+
+func (p pattern) walk(v interface{}, path []string, fn func(path string, v interface{})) {
+	if len(p) == 0 {
+		fn(strings.Join(path, "."), v)
+		return
+	}
+	s := p[0]
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if s.re == nil {
+			if child, found := v[s.key]; found {
+				p[1:].walk(child, append(path[:len(path):len(path)], s.key), fn)
+			}
+			return
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			if s.re.MatchString(k) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			p[1:].walk(v[k], append(path[:len(path):len(path)], k), fn)
+		}
+	case []interface{}:
+		// Array items are referenced with the [index] syntax of gojq.
+		for i, child := range v {
+			idx := strconv.Itoa(i)
+			if s.re != nil && s.re.MatchString(idx) || s.key == "["+idx+"]" {
+				p[1:].walk(child, append(path[:len(path):len(path)], "["+idx+"]"), fn)
+			}
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package data
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/elgs/gojq"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		s    string
+		sep  byte
+		want []string
+	}{
+		{"a.b.c", '.', []string{"a", "b", "c"}},
+		{`a."b.c".d`, '.', []string{"a", `"b.c"`, "d"}},
+		{`a.'b\'.c'.d`, '.', []string{"a", `'b\'.c'`, "d"}},
+		{"a./^x.y$/.b", '.', []string{"a", "/^x.y$/", "b"}},
+		{"/a.b/.c", '.', []string{"/a.b/", "c"}},
+		{"a+(b+c)+d", '+', []string{"a", "(b+c)", "d"}},
+		{"avg:(a:b)", ':', []string{"avg", "(a:b)"}},
+		{`rate:"job:x:rate5m"`, ':', []string{"rate", `"job:x:rate5m"`}},
+		{`up{code="a+b"}+x`, '+', []string{`up{code="a+b"}`, "x"}},
+	}
+	for _, tt := range tests {
+		if got := split(tt.s, tt.sep); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("split(%s, %c) = %q, want %q", tt.s, tt.sep, got, tt.want)
+		}
+	}
+}
+
+func TestGlobRegexp(t *testing.T) {
+	tests := []struct {
+		glob    string
+		match   []string
+		noMatch []string
+	}{
+		{glob: "plain"},
+		{glob: "a?b"},
+		{glob: "*", match: []string{"", "a", "a.b"}},
+		{glob: "api_*", match: []string{"api_", "api_users"}, noMatch: []string{"web_api_users"}},
+		{glob: "*_total", match: []string{"requests_total"}, noMatch: []string{"requests_total_x"}},
+		{glob: "/users?id=*", match: []string{"/users?id=1"}, noMatch: []string{"/usersXid=1"}},
+		{glob: "a.*", match: []string{"a.b"}, noMatch: []string{"ab"}},
+		{glob: `'*'`},
+	}
+	for _, tt := range tests {
+		re := globRegexp(tt.glob)
+		if len(tt.match) == 0 {
+			if re != nil {
+				t.Errorf("globRegexp(%s) = %v, want nil", tt.glob, re)
+			}
+			continue
+		}
+		if re == nil {
+			t.Errorf("globRegexp(%s) = nil", tt.glob)
+			continue
+		}
+		for _, s := range tt.match {
+			if !re.MatchString(s) {
+				t.Errorf("globRegexp(%s) does not match %s", tt.glob, s)
+			}
+		}
+		for _, s := range tt.noMatch {
+			if re.MatchString(s) {
+				t.Errorf("globRegexp(%s) matches %s", tt.glob, s)
+			}
+		}
+	}
+}
+
+func TestPatternMatch(t *testing.T) {
+	jq, err := gojq.NewStringQuery(`{
+		"requests": {"api_users": {"count": 1}, "api_orders": {"count": 2}, "web": {"count": 3}},
+		"a.b": {"c": 4},
+		"p?": 5,
+		"items": [{"v": 6}, {"v": 7}]
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path    string
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{path: "requests.api_users.count"},
+		{path: "p?"},
+		{path: `"a.b".c`},
+		{path: "requests.*.count", want: map[string]interface{}{
+			"requests.api_orders.count": 2.0,
+			"requests.api_users.count":  1.0,
+			"requests.web.count":        3.0,
+		}},
+		{path: "requests./^api_/.count", want: map[string]interface{}{
+			"requests.api_orders.count": 2.0,
+			"requests.api_users.count":  1.0,
+		}},
+		{path: "requests.api_*.count", want: map[string]interface{}{
+			"requests.api_orders.count": 2.0,
+			"requests.api_users.count":  1.0,
+		}},
+		{path: `"a.b".*`, want: map[string]interface{}{
+			"a.b.c": 4.0,
+		}},
+		{path: "items.*.v", want: map[string]interface{}{
+			"items.[0].v": 6.0,
+			"items.[1].v": 7.0,
+		}},
+		{path: "items.[1].*", want: map[string]interface{}{
+			"items.[1].v": 7.0,
+		}},
+		{path: "requests./[/.count", wantErr: true},
+	}
+	for _, tt := range tests {
+		p, err := parsePattern(tt.path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parsePattern(%s): error %v, want error %v", tt.path, err, tt.wantErr)
+			continue
+		}
+		if tt.want == nil {
+			if p != nil {
+				t.Errorf("parsePattern(%s) = %v, want nil", tt.path, p)
+			}
+			continue
+		}
+		got := map[string]interface{}{}
+		var paths []string
+		p.match(jq.Data, func(path string, v interface{}) {
+			got[path] = v
+			paths = append(paths, path)
+		})
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("match(%s) = %v, want %v", tt.path, got, tt.want)
+		}
+		for i := 1; i < len(paths); i++ {
+			if paths[i-1] > paths[i] {
+				t.Errorf("match(%s): paths not sorted: %v", tt.path, paths)
+				break
+			}
+		}
+	}
+}
@@ -1,9 +1,15 @@
 package data
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/elgs/gojq"
 )
@@ -14,81 +20,498 @@ type Getter interface {
 	Get() (*gojq.JQ, error)
 }
 
-// Points is a series of Size data points gathered from Source.
+// MissedError is returned by a Getter when a sample could not be gathered but
+// the source is still expected to recover. Missed samples are recorded as
+// gaps.
+type MissedError struct {
+	Err error
+}
+
+func (e *MissedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *MissedError) Unwrap() error {
+	return e.Err
+}
+
+// Points is a series of Size data points gathered from Source and Sources.
 type Points struct {
 	// Size is the number of data point to store per metric.
 	Size   int
 	Source Getter
+	// Sources are additional named sources, read concurrently with Source.
+	// A field reads the source named by its Source, or Source when empty.
+	Sources map[string]Getter
+	// Record receives every sample read from the sources as a line of JSON,
+	// with its source name and time of reception, for FromReplay to play the
+	// session back.
+	Record io.Writer
+	// TimeField is the path of the field holding the timestamp of each
+	// sample. The time of reception is used when empty. Samples with a
+	// missing or invalid time are recorded as missed.
+	TimeField string
 
-	points map[string][]float64
-	last   map[string]float64
-	mu     sync.Mutex
+	points    map[string][]float64
+	times     map[string][]time.Time
+	last      map[string]sample
+	windows   map[string][]float64 // last values of fields with a statistic
+	ewma      map[string]float64
+	states    map[string][]string  // names of the states of state fields
+	failures  map[string]int       // number of unreadable samples per field
+	expanded  map[string][]Field   // fields matched by each pattern field
+	matched   map[string]bool      // IDs of the fields in expanded
+	lastTime  time.Time            // time of the last sample
+	lastSeen  time.Time            // time of reception of the last sample
+	downSince map[string]time.Time // per source name
+	downErr   map[string]error
+	mu        sync.Mutex
 }
 
-// Run get data from the source and capture metrics following specs.
+// Run get data from the sources and capture metrics following specs, until
+// all sources are exhausted.
 func (p *Points) Run(specs []Spec) error {
-	for {
-		jq, err := p.Source.Get()
-		if err != nil {
+	samples, stop := p.read()
+	defer stop()
+	for s := range samples {
+		jq, err := s.jq, s.err
+		t := time.Now()
+		if !s.t.IsZero() {
+			t = s.t
+		}
+		var missed *MissedError
+		if err != nil && !errors.As(err, &missed) {
+			if s.source != "" {
+				return fmt.Errorf("input error: %s: %v", s.source, err)
+			}
 			return fmt.Errorf("input error: %v", err)
 		}
-		if jq == nil {
-			break
+		if p.Record != nil {
+			if err := p.record(s, t); err != nil {
+				return fmt.Errorf("record error: %v", err)
+			}
+		}
+		if missed != nil {
+			p.miss(specs, s.source, t, missed.Err)
+			continue
+		}
+		recv := t
+		if p.TimeField != "" {
+			if t, err = p.timestamp(jq); err != nil {
+				// Samples without a valid time are missed like unreachable
+				// ones rather than ending the session.
+				p.miss(specs, s.source, recv, err)
+				continue
+			}
 		}
+		p.up(s.source, recv)
+		p.mu.Lock()
+		p.lastTime = t
+		p.mu.Unlock()
 		for _, spec := range specs {
 			for _, f := range spec.Fields {
-				v, err := jq.Query(f.Name)
-				if err != nil {
-					return fmt.Errorf("cannot get %s: %v", f.Name, err)
+				if f.Source != s.source {
+					continue
 				}
-				n, ok := v.(float64)
-				if !ok {
-					return fmt.Errorf("invalid type %s: %T", f.Name, v)
+				if f.pattern != nil {
+					p.pushPattern(f, jq, t)
+					continue
 				}
-				p.push(f.ID, n, f.IsCounter)
+				var n float64
+				if f.expr != nil {
+					n, err = f.expr.eval(jq)
+				} else if v, qerr := query(jq, f.Name); qerr != nil {
+					err = fmt.Errorf("cannot get %s: %v", f.Name, qerr)
+				} else {
+					switch v := p.normalize(f, v).(type) {
+					case float64:
+						n = v
+					case []interface{}:
+						p.pushArray(f, t, v)
+						continue
+					default:
+						if state, ok := stateOf(v); ok {
+							p.pushMissing(f, t, nil)
+							p.pushState(f, t, state)
+							continue
+						}
+						err = fmt.Errorf("invalid type %s: %T", f.Name, v)
+					}
+				}
+				if err != nil {
+					// Optional, late or null fields are plotted as gaps.
+					p.fail(f)
+					n = math.NaN()
+				}
+				// The items of a field which held an array are gaps now.
+				p.pushMissing(f, t, nil)
+				p.push(f, t, n)
 			}
 		}
 	}
 	return nil
 }
 
-func (p *Points) push(name string, value float64, counter bool) {
+// pushPattern records the values of the fields matched by the pattern field
+// f, adding the fields matched for the first time and recording a gap for the
+// ones which disappeared.
+func (p *Points) pushPattern(f Field, jq *gojq.JQ, t time.Time) {
+	seen := map[string]bool{}
+	f.pattern.match(jq.Data, func(path string, v interface{}) {
+		n, ok := v.(float64)
+		if !ok {
+			// Patterns are likely to match other values than numbers.
+			return
+		}
+		m := p.match(f, path)
+		seen[m.ID] = true
+		p.push(m, t, n)
+	})
+	p.pushMissing(f, t, seen)
+}
+
+// pushArray records the numbers of the array items of the field f as one
+// field per index, as if f was a pattern matching all the items. The series
+// of f itself, kept for the samples where it is not an array, gets a gap.
+func (p *Points) pushArray(f Field, t time.Time, items []interface{}) {
+	seen := map[string]bool{}
+	for i, v := range items {
+		n, ok := v.(float64)
+		if !ok {
+			continue
+		}
+		m := p.match(f, fmt.Sprintf("%s.[%d]", f.Name, i))
+		seen[m.ID] = true
+		p.push(m, t, n)
+	}
+	p.pushMissing(f, t, seen)
+	p.mu.Lock()
+	_, found := p.points[f.ID]
+	p.mu.Unlock()
+	if found {
+		p.push(f, t, math.NaN())
+	}
+}
+
+// pushMissing records a gap for the fields matched by f which are not in
+// seen.
+func (p *Points) pushMissing(f Field, t time.Time, seen map[string]bool) {
+	p.mu.Lock()
+	fields := p.expanded[f.ID]
+	p.mu.Unlock()
+	for _, m := range fields {
+		if !seen[m.ID] {
+			p.push(m, t, math.NaN())
+		}
+	}
+}
+
+// match returns the field matched by the pattern or array field f at path,
+// adding it to the fields of f when new.
+func (p *Points) match(f Field, path string) Field {
+	m := f
+	m.ID = f.ID + "/" + path
+	m.Name = path
+	m.Label = ""
+	m.Color = ""
+	m.pattern = nil
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	d := p.getLocked(name)
-	if counter {
-		var diff float64
-		if last := p.last[name]; last > 0 && last < value {
-			diff = value - last
+	if p.expanded == nil {
+		p.expanded = map[string][]Field{}
+		p.matched = map[string]bool{}
+	}
+	if !p.matched[m.ID] {
+		p.matched[m.ID] = true
+		p.expanded[f.ID] = append(p.expanded[f.ID], m)
+	}
+	return m
+}
+
+// Fields returns the fields of spec, with pattern and array fields replaced
+// by the fields they matched so far. An array field is kept along with its
+// items while it has values from samples where it was not an array.
+func (p *Points) Fields(spec Spec) []Field {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fields := make([]Field, 0, len(spec.Fields))
+	for _, f := range spec.Fields {
+		if f.pattern == nil && (p.expanded[f.ID] == nil || hasValues(p.points[f.ID])) {
+			fields = append(fields, f)
+		}
+		fields = append(fields, p.expanded[f.ID]...)
+	}
+	return fields
+}
+
+// hasValues returns true if d holds at least a value other than a gap.
+func hasValues(d []float64) bool {
+	for _, v := range d {
+		if !math.IsNaN(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// query resolves path in jq. A top level key matching path verbatim takes
+// precedence so keys containing dots or quotes, like Prometheus series, can be
+// referenced as is.
+func query(jq *gojq.JQ, path string) (interface{}, error) {
+	if m, ok := jq.Data.(map[string]interface{}); ok {
+		if v, found := m[path]; found {
+			return v, nil
 		}
-		p.last[name] = value
-		value = diff
 	}
-	d = append(append(make([]float64, 0, p.Size), d[1:]...), value)
-	p.points[name] = d
+	return jq.Query(path)
 }
 
-// Get gets the points vector for name.
+// queryNumber resolves path in jq, which must hold a number.
+func queryNumber(jq *gojq.JQ, path string) (float64, error) {
+	v, err := query(jq, path)
+	if err != nil {
+		return 0, fmt.Errorf("cannot get %s: %v", path, err)
+	}
+	n, ok := v.(float64)
+	if !ok {
+		return 0, fmt.Errorf("invalid type %s: %T", path, v)
+	}
+	return n, nil
+}
+
+// fail counts a sample of f which could not be read.
+func (p *Points) fail(f Field) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.failures == nil {
+		p.failures = map[string]int{}
+	}
+	p.failures[f.ID]++
+}
+
+// Errors returns the number of samples of the field id which were missing or
+// not a number.
+func (p *Points) Errors(id string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.failures[id]
+}
+
+// timestamp reads the sample time from the TimeField of jq. Numbers are
+// interpreted as a Unix time in seconds, milliseconds, microseconds or
+// nanoseconds depending on their magnitude, strings as RFC 3339 dates.
+func (p *Points) timestamp(jq *gojq.JQ) (time.Time, error) {
+	v, err := query(jq, p.TimeField)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot get %s: %v", p.TimeField, err)
+	}
+	switch v := v.(type) {
+	case float64:
+		switch {
+		case v > 1e17:
+			return time.Unix(0, int64(v)), nil
+		case v > 1e14:
+			return time.UnixMicro(int64(v)), nil
+		case v > 1e11:
+			return time.UnixMilli(int64(v)), nil
+		}
+		sec, frac := math.Modf(v)
+		return time.Unix(int64(sec), int64(frac*1e9)), nil
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time %s: %v", p.TimeField, err)
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid type %s: %T", p.TimeField, v)
+}
+
+// miss records a gap for all fields of the source name, missed at now, and
+// marks it as down.
+func (p *Points) miss(specs []Spec, name string, now time.Time, err error) {
+	p.mu.Lock()
+	if p.downErr == nil {
+		p.downSince = map[string]time.Time{}
+		p.downErr = map[string]error{}
+	}
+	if p.downErr[name] == nil {
+		p.downSince[name] = now
+	}
+	p.downErr[name] = err
+	// Estimate the time of the missed sample in the time base of the samples,
+	// which may not be the wall clock when TimeField is set.
+	t := now
+	if !p.lastTime.IsZero() {
+		t = p.lastTime.Add(now.Sub(p.lastSeen))
+	}
+	p.mu.Unlock()
+	for _, spec := range specs {
+		for _, f := range p.Fields(spec) {
+			if f.Source == name {
+				p.push(f, t, math.NaN())
+			}
+		}
+	}
+}
+
+func (p *Points) up(name string, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.downErr, name)
+	p.lastSeen = now
+}
+
+// Down returns the time since when a source is failing with the last error
+// it returned. The errors of named sources are prefixed with their name, and
+// joined when several sources are down. The returned error is nil when all
+// sources are up.
+func (p *Points) Down() (since time.Time, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	names := make([]string, 0, len(p.downErr))
+	for name := range p.downErr {
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return time.Time{}, nil
+	}
+	sort.Strings(names)
+	msgs := make([]string, 0, len(names))
+	for _, name := range names {
+		if s := p.downSince[name]; since.IsZero() || s.Before(since) {
+			since = s
+		}
+		if name == "" {
+			msgs = append(msgs, p.downErr[name].Error())
+		} else {
+			msgs = append(msgs, name+": "+p.downErr[name].Error())
+		}
+	}
+	if len(msgs) == 1 && names[0] == "" {
+		return since, p.downErr[""]
+	}
+	return since, errors.New(strings.Join(msgs, ", "))
+}
+
+// sample is a raw value of a counter field with its time.
+type sample struct {
+	value float64
+	t     time.Time
+}
+
+// push appends value taken at t to the series of f. A NaN value is a gap, as
+// are infinite values, such as the +Inf of Prometheus histograms, which cannot
+// be plotted.
+func (p *Points) push(f Field, t time.Time, value float64) {
+	if math.IsInf(value, 0) {
+		value = math.NaN()
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.states[f.ID] != nil {
+		// Numbers are states like others in a state field.
+		if !math.IsNaN(value) {
+			value = p.stateLocked(f.ID, strconv.FormatFloat(value, 'f', -1, 64))
+		}
+		p.appendLocked(f.ID, t, value)
+		return
+	}
+	if f.IsCounter || f.IsRate {
+		value = p.deltaLocked(f, t, value)
+	}
+	if f.Stat != "" {
+		value = p.statLocked(f, value)
+	}
+	p.appendLocked(f.ID, t, value)
+}
+
+// appendLocked appends value taken at t to the series name, dropping the
+// oldest value when full.
+//
+// Values are appended past the end of the slices returned to readers, which
+// never see them change. Dropping the oldest value by reslicing lets append
+// reallocate the arrays only once in a while.
+func (p *Points) appendLocked(name string, t time.Time, value float64) {
+	ts, d := p.getLocked(name)
+	ts, d = append(ts, t), append(d, value)
+	if len(d) > p.Size {
+		ts, d = ts[len(ts)-p.Size:], d[len(d)-p.Size:]
+	}
+	p.times[name], p.points[name] = ts, d
+}
+
+// deltaLocked computes the increase of the counter f since its previous
+// sample, divided by the elapsed seconds if f is a rate.
+//
+// A value lower than the previous one is a counter reset: the counter is
+// assumed to have restarted from zero. The first sample of a rate, or the one
+// following a gap, has no previous value and is a gap. The first sample of a
+// counter is zero.
+func (p *Points) deltaLocked(f Field, t time.Time, value float64) float64 {
+	if p.last == nil {
+		p.last = make(map[string]sample)
+	}
+	last, found := p.last[f.ID]
+	// A gap is kept as the previous value so the difference is not computed
+	// over it.
+	p.last[f.ID] = sample{value: value, t: t}
+	if math.IsNaN(value) || found && math.IsNaN(last.value) {
+		return math.NaN()
+	}
+	if !found {
+		if f.IsRate {
+			return math.NaN()
+		}
+		return 0
+	}
+	diff := value - last.value
+	if diff < 0 {
+		diff = value
+	}
+	if f.IsRate {
+		elapsed := t.Sub(last.t).Seconds()
+		if elapsed <= 0 {
+			return math.NaN()
+		}
+		diff /= elapsed
+	}
+	return diff
+}
+
+// Get gets the points vector for name. Gaps are represented as NaN values.
 func (p *Points) Get(name string) []float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, d := p.getLocked(name)
+	return d
+}
+
+// Series gets the points vector for name with the time of each point.
+func (p *Points) Series(name string) ([]time.Time, []float64) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	return p.getLocked(name)
 }
 
-func (p *Points) getLocked(name string) []float64 {
+func (p *Points) getLocked(name string) ([]time.Time, []float64) {
 	if p.points == nil {
 		p.points = make(map[string][]float64, 1)
-		p.last = make(map[string]float64)
-	}
-	d, found := p.points[name]
-	if !found {
-		d = make([]float64, p.Size)
-		p.points[name] = d
+		p.times = make(map[string][]time.Time, 1)
 	}
-	return d
+	return p.times[name], p.points[name]
 }
 
-// Close calls Close on Source.
+// Close calls Close on Source and Sources.
 func (p *Points) Close() error {
-	return p.Source.Close()
+	var err error
+	if p.Source != nil {
+		err = p.Source.Close()
+	}
+	for _, g := range p.Sources {
+		if cerr := g.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
 }
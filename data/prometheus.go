@@ -0,0 +1,132 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/elgs/gojq"
+)
+
+// FromPrometheus fetch metrics in the Prometheus text exposition format from
+// url every opts.Interval and keep size points.
+//
+// Each series is exposed as a top level field named after the metric name
+// followed by its labels as they appear in the exposition, without spaces
+// (eg: http_requests_total{code="200",method="get"}).
+func FromPrometheus(url string, opts HTTPOptions, size int) (*Points, error) {
+	h, err := newHTTPSource(url, parsePrometheus, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Points{
+		Size:   size,
+		Source: h,
+	}, nil
+}
+
+// parsePrometheus parses the Prometheus text exposition format into a flat
+// object of series name to value.
+func parsePrometheus(b []byte) (*gojq.JQ, error) {
+	metrics := map[string]interface{}{}
+	scan := bufio.NewScanner(bytes.NewReader(b))
+	for n := 1; scan.Scan(); n++ {
+		line := strings.TrimSpace(scan.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		name, value, err := parsePrometheusSample(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+		metrics[name] = value
+	}
+	if err := scan.Err(); err != nil {
+		return nil, err
+	}
+	return gojq.NewQuery(metrics), nil
+}
+
+// parsePrometheusSample parses a single sample line in the form of
+// name{label="value",...} value [timestamp].
+func parsePrometheusSample(line string) (name string, value float64, err error) {
+	i := strings.IndexAny(line, "{ \t")
+	if i == -1 {
+		return "", 0, fmt.Errorf("missing value: %s", line)
+	}
+	name = line[:i]
+	rest := line[i:]
+	if rest[0] == '{' {
+		var labels string
+		if labels, rest, err = parsePrometheusLabels(rest[1:]); err != nil {
+			return "", 0, err
+		}
+		if labels != "" {
+			name += "{" + labels + "}"
+		}
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return "", 0, fmt.Errorf("missing value: %s", line)
+	}
+	value, err = parsePrometheusValue(fields[0])
+	return name, value, err
+}
+
+// parsePrometheusLabels parses a label set up to the closing brace and
+// returns it normalized with no spaces along with the remaining of the line.
+func parsePrometheusLabels(s string) (labels, rest string, err error) {
+	var b strings.Builder
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if s == "" {
+			return "", "", fmt.Errorf("unterminated label set")
+		}
+		if s[0] == '}' {
+			return b.String(), s[1:], nil
+		}
+		eq := strings.IndexByte(s, '=')
+		if eq == -1 {
+			return "", "", fmt.Errorf("invalid label: %s", s)
+		}
+		if b.Len() > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strings.TrimSpace(s[:eq]))
+		b.WriteByte('=')
+		s = strings.TrimLeft(s[eq+1:], " \t")
+		if s == "" || s[0] != '"' {
+			return "", "", fmt.Errorf("unquoted label value: %s", s)
+		}
+		// Find the closing quote, skipping escaped characters.
+		end := 1
+		for ; end < len(s) && s[end] != '"'; end++ {
+			if s[end] == '\\' {
+				end++
+			}
+		}
+		if end >= len(s) {
+			return "", "", fmt.Errorf("unterminated label value: %s", s)
+		}
+		b.WriteString(s[:end+1])
+		s = strings.TrimLeft(s[end+1:], " \t")
+		if s != "" && s[0] == ',' {
+			s = s[1:]
+		}
+	}
+}
+
+func parsePrometheusValue(s string) (float64, error) {
+	switch s {
+	case "+Inf":
+		return math.Inf(1), nil
+	case "-Inf":
+		return math.Inf(-1), nil
+	case "NaN":
+		return math.NaN(), nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
@@ -0,0 +1,87 @@
+package data
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParsePrometheusSample(t *testing.T) {
+	tests := []struct {
+		line    string
+		name    string
+		value   float64
+		wantErr bool
+	}{
+		{line: `go_goroutines 12`, name: `go_goroutines`, value: 12},
+		{line: `go_goroutines 12 1395066363000`, name: `go_goroutines`, value: 12},
+		{line: `up{} 1`, name: `up`, value: 1},
+		{line: `http_requests_total{code="200",method="get"} 1027`, name: `http_requests_total{code="200",method="get"}`, value: 1027},
+		{line: `http_requests_total{ code = "200" , method="get", } 3`, name: `http_requests_total{code="200",method="get"}`, value: 3},
+		{line: `rate:job:requests:rate5m{job="api"} 1.5e3`, name: `rate:job:requests:rate5m{job="api"}`, value: 1500},
+		// Label values holding escapes, quotes, braces and spaces.
+		{line: `file_time{path="C:\\DIR\\FILE.TXT",error="Cannot find file:\n\"FILE.TXT\""} 1.458255915e9`, name: `file_time{path="C:\\DIR\\FILE.TXT",error="Cannot find file:\n\"FILE.TXT\""}`, value: 1.458255915e9},
+		{line: `msg{text="a } b, c=\"d\""} 2`, name: `msg{text="a } b, c=\"d\""}`, value: 2},
+		// Special values.
+		{line: `latency_bucket{le="+Inf"} 42`, name: `latency_bucket{le="+Inf"}`, value: 42},
+		{line: `max +Inf`, name: `max`, value: math.Inf(1)},
+		{line: `min -Inf`, name: `min`, value: math.Inf(-1)},
+		{line: `quantile{quantile="0.99"} NaN`, name: `quantile{quantile="0.99"}`, value: math.NaN()},
+		// Invalid lines.
+		{line: `go_goroutines`, wantErr: true},
+		{line: `go_goroutines{} `, wantErr: true},
+		{line: `go_goroutines abc`, wantErr: true},
+		{line: `up{job="api" 1`, wantErr: true},
+		{line: `up{job="api} 1`, wantErr: true},
+		{line: `up{job=api} 1`, wantErr: true},
+		{line: `up{job} 1`, wantErr: true},
+	}
+	for _, tt := range tests {
+		name, value, err := parsePrometheusSample(tt.line)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parsePrometheusSample(%s): error %v, want error %v", tt.line, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if name != tt.name {
+			t.Errorf("parsePrometheusSample(%s): name %s, want %s", tt.line, name, tt.name)
+		}
+		if value != tt.value && !(math.IsNaN(value) && math.IsNaN(tt.value)) {
+			t.Errorf("parsePrometheusSample(%s): value %v, want %v", tt.line, value, tt.value)
+		}
+	}
+}
+
+func TestParsePrometheus(t *testing.T) {
+	b := []byte(`# HELP http_requests_total The total number of HTTP requests.
+# TYPE http_requests_total counter
+http_requests_total{method="post",code="200"} 1027 1395066363000
+http_requests_total{method="post",code="400"}    3 1395066363000
+
+  # A comment with leading spaces.
+go_goroutines 12
+`)
+	jq, err := parsePrometheus(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]float64{
+		`http_requests_total{method="post",code="200"}`: 1027,
+		`http_requests_total{method="post",code="400"}`: 3,
+		`go_goroutines`: 12,
+	}
+	m := jq.Data.(map[string]interface{})
+	if len(m) != len(want) {
+		t.Errorf("parsePrometheus: %d series, want %d: %v", len(m), len(want), m)
+	}
+	for name, value := range want {
+		if got, ok := m[name].(float64); !ok || got != value {
+			t.Errorf("parsePrometheus: %s = %v, want %v", name, m[name], value)
+		}
+	}
+
+	if _, err := parsePrometheus([]byte("ok 1\nbroken{\n")); err == nil {
+		t.Error("parsePrometheus: expected an error for an unterminated label set")
+	}
+}
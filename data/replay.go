@@ -0,0 +1,145 @@
+package data
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/elgs/gojq"
+)
+
+// record is a line of a recorded session: a sample, or a missed sample when
+// Error is set, with the time it was received at and the name of its source.
+type record struct {
+	Time   time.Time   `json:"time"`
+	Source string      `json:"source,omitempty"`
+	Sample interface{} `json:"sample,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// record writes s received at t to Record.
+func (p *Points) record(s sourceSample, t time.Time) error {
+	r := record{Time: t, Source: s.source}
+	var missed *MissedError
+	if errors.As(s.err, &missed) {
+		r.Error = missed.Err.Error()
+	} else {
+		r.Sample = s.jq.Data
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = p.Record.Write(append(b, '\n'))
+	return err
+}
+
+// sampler is implemented by Getters knowing the source and time of their
+// samples, which are used instead of the default source and the time of
+// reception.
+type sampler interface {
+	// sample returns the next sample, or false once exhausted.
+	sample() (sourceSample, bool)
+}
+
+// replaySource plays a session recorded with Points.Record back.
+type replaySource struct {
+	c      chan sourceSample
+	done   chan struct{}
+	closer sync.Once
+}
+
+// FromReplay plays the session recorded in the file at path back, keeping
+// size points. Samples are replayed speed times faster than they were
+// recorded, or at once when speed is zero. They keep their recorded time and
+// source name.
+func FromReplay(path string, speed float64, size int) (*Points, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &replaySource{
+		c:    make(chan sourceSample),
+		done: make(chan struct{}),
+	}
+	go func() {
+		defer f.Close()
+		r.run(f, speed)
+	}()
+	return &Points{
+		Size:   size,
+		Source: r,
+	}, nil
+}
+
+func (r *replaySource) run(in io.Reader, speed float64) {
+	defer close(r.c)
+	scan := bufio.NewScanner(in)
+	scan.Buffer(nil, 64<<20)
+	var first time.Time
+	start := time.Now()
+	for n := 1; scan.Scan(); n++ {
+		var rec record
+		if err := json.Unmarshal(scan.Bytes(), &rec); err != nil {
+			r.send(sourceSample{err: fmt.Errorf("invalid record on line %d: %v", n, err)})
+			return
+		}
+		if first.IsZero() {
+			first = rec.Time
+		}
+		if speed > 0 {
+			wait := time.Until(start.Add(time.Duration(float64(rec.Time.Sub(first)) / speed)))
+			select {
+			case <-time.After(wait):
+			case <-r.done:
+				return
+			}
+		}
+		s := sourceSample{source: rec.Source, t: rec.Time}
+		if rec.Error != "" {
+			s.err = &MissedError{Err: errors.New(rec.Error)}
+		} else {
+			s.jq = gojq.NewQuery(rec.Sample)
+		}
+		if !r.send(s) {
+			return
+		}
+	}
+	if err := scan.Err(); err != nil {
+		r.send(sourceSample{err: err})
+	}
+}
+
+func (r *replaySource) send(s sourceSample) bool {
+	select {
+	case r.c <- s:
+		return true
+	case <-r.done:
+		return false
+	}
+}
+
+func (r *replaySource) sample() (sourceSample, bool) {
+	s, ok := <-r.c
+	return s, ok
+}
+
+func (r *replaySource) Get() (*gojq.JQ, error) {
+	s, ok := r.sample()
+	if !ok {
+		return nil, nil
+	}
+	return s.jq, s.err
+}
+
+func (r *replaySource) Close() error {
+	r.closer.Do(func() {
+		close(r.done)
+	})
+	return nil
+}
@@ -0,0 +1,63 @@
+package data
+
+import (
+	"sync"
+	"time"
+
+	"github.com/elgs/gojq"
+)
+
+// sourceSample is a sample, or the error of a failed read, of the source
+// named source. Its time is the time of reception when t is zero.
+type sourceSample struct {
+	source string
+	t      time.Time
+	jq     *gojq.JQ
+	err    error
+}
+
+// read reads Source and Sources concurrently. The returned channel is closed
+// once all sources are exhausted. The sources stop being read once stop is
+// called.
+func (p *Points) read() (samples <-chan sourceSample, stop func()) {
+	sources := make(map[string]Getter, len(p.Sources)+1)
+	for name, g := range p.Sources {
+		sources[name] = g
+	}
+	if p.Source != nil {
+		sources[""] = p.Source
+	}
+	c := make(chan sourceSample)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for name, g := range sources {
+		wg.Add(1)
+		go func(name string, g Getter) {
+			defer wg.Done()
+			for {
+				var s sourceSample
+				if sg, ok := g.(sampler); ok && name == "" {
+					var more bool
+					if s, more = sg.sample(); !more {
+						return
+					}
+				} else {
+					s = sourceSample{source: name}
+					if s.jq, s.err = g.Get(); s.jq == nil && s.err == nil {
+						return
+					}
+				}
+				select {
+				case c <- s:
+				case <-done:
+					return
+				}
+			}
+		}(name, g)
+	}
+	go func() {
+		wg.Wait()
+		close(c)
+	}()
+	return c, func() { close(done) }
+}
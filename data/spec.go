@@ -2,56 +2,158 @@ package data
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 )
 
 // Spec specify a list of field for a single graph.
 type Spec struct {
 	Fields []Field
+	// Title is displayed at the top of the graph when set.
+	Title string
+	// Height is the share of the dashboard height taken by the graph,
+	// relative to the other graphs. A zero height counts as one.
+	Height int
 }
 
 // Field describe a field in a graph.
 type Field struct {
-	ID        string
-	Name      string
+	ID   string
+	Name string
+	// Source is the name of the source the field is read from, or empty for
+	// the default source.
+	Source string
+	// Label replaces the name in the legend when set.
+	Label string
+	// Color is the hexadecimal color (eg: #ff0000) of the line, picked
+	// automatically when empty.
+	Color     string
 	IsCounter bool
+	IsRate    bool
 	IsMarker  bool
+	// Stat is the statistic plotted instead of the value, computed over the
+	// last Window values: avg, ewma, min, max or p for the Percentile.
+	Stat       string
+	Percentile float64
+	Window     int
+
+	// pattern is set when Name has glob or regexp segments, in which case
+	// the field expands into one field per matching key.
+	pattern pattern
+	// expr is set when Name is an arithmetic expression over other paths.
+	expr expr
+}
+
+// defaultWindow is the number of values a statistic is computed over when not
+// specified.
+const defaultWindow = 10
+
+// parseStat parses a statistic option in the stat[=window] form, where stat
+// is avg, ewma, min, max or a percentile like p95.
+func (f *Field) parseStat(o string) error {
+	stat, window, hasWindow := strings.Cut(o, "=")
+	switch {
+	case stat == "avg" || stat == "ewma" || stat == "min" || stat == "max":
+	case strings.HasPrefix(stat, "p"):
+		p, ok := parsePercentile(stat)
+		if !ok {
+			return fmt.Errorf("invalid field option: %s", o)
+		}
+		f.Percentile = p
+		stat = "p"
+	default:
+		return fmt.Errorf("invalid field option: %s", o)
+	}
+	if f.Stat != "" {
+		return fmt.Errorf("invalid field option: %s: only one statistic per field", o)
+	}
+	f.Stat, f.Window = stat, defaultWindow
+	if hasWindow {
+		n, err := strconv.Atoi(window)
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid field option: %s: invalid window", o)
+		}
+		f.Window = n
+	}
+	return nil
+}
+
+// isSourceName returns true if s is a valid source name: letters, digits,
+// dashes and underscores.
+func isSourceName(s string) bool {
+	for _, c := range s {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return s != ""
 }
 
 // ParseSpec parses a graph specification. Each spec is a string with one or
 // more JSON path separated by + with fields options prefixed with colon and
-// separated by commas.
+// separated by commas. Path segments can be globs (eg: requests.*.count) or
+// regular expressions between slashes (eg: requests./^api_/.count). A path
+// can also be an arithmetic expression over other paths between parentheses
+// (eg: (errors/requests)).
+// A path prefixed by a source name and @ (eg: canary@memstats.HeapInuse) is
+// read from the named source. Expressions are computed over the samples of a
+// single source, so the paths they reference cannot have such a prefix.
 func ParseSpec(args []string) ([]Spec, error) {
 	specs := make([]Spec, 0, len(args))
 	for i, v := range args {
 		spec := Spec{}
-		for j, name := range strings.Split(v, "+") {
-			var isCounter bool
-			var isMarker bool
+		for j, name := range split(v, '+') {
 			if strings.HasPrefix(name, "marker:counter:") {
 				// Backward compat.
 				name = strings.Replace(name, "marker:counter:", "marker,counter:", 1)
 			}
-			if idx := strings.IndexByte(name, ':'); idx != -1 {
-				options := strings.Split(name[:idx], ",")
-				name = name[idx+1:]
-				for _, o := range options {
-					switch o {
-					case "counter":
-						isCounter = true
-					case "marker":
-						isMarker = true
-					default:
-						return nil, fmt.Errorf("invalid field option: %s", o)
+			var options []string
+			// Colons in quotes are part of the path, so that names like
+			// Prometheus recording rules can be referenced quoted (eg:
+			// "job:requests:rate5m").
+			if parts := split(name, ':'); len(parts) > 1 {
+				options = strings.Split(parts[0], ",")
+				name = name[len(parts[0])+1:]
+			}
+			f := Field{
+				ID:   fmt.Sprintf("%d.%d.%s", i, j, name),
+				Name: name,
+			}
+			if idx := strings.IndexByte(name, '@'); idx > 0 && isSourceName(name[:idx]) {
+				f.Source, f.Name = name[:idx], name[idx+1:]
+				name = f.Name
+			}
+			for _, o := range options {
+				switch o {
+				case "counter":
+					f.IsCounter = true
+				case "rate":
+					f.IsRate = true
+				case "marker":
+					f.IsMarker = true
+				default:
+					if err := f.parseStat(o); err != nil {
+						return nil, err
 					}
 				}
 			}
-			spec.Fields = append(spec.Fields, Field{
-				ID:        fmt.Sprintf("%d.%d.%s", i, j, name),
-				Name:      name,
-				IsCounter: isCounter,
-				IsMarker:  isMarker,
-			})
+			var err error
+			switch {
+			case isExpr(name):
+				if f.expr, err = parseExpr(name); err == nil {
+					if path := sourcePath(f.expr); path != "" {
+						err = fmt.Errorf("invalid expression %s: paths cannot have a source prefix (%s), prefix the whole expression instead (eg: canary@(errors/requests))", name, path)
+					}
+				}
+			case looksLikeExpr(name):
+				err = fmt.Errorf("ambiguous path %s: expressions must be between parentheses (eg: (errors/requests)) and keys holding operators quoted", name)
+			default:
+				f.pattern, err = parsePattern(name)
+			}
+			if err != nil {
+				return nil, err
+			}
+			spec.Fields = append(spec.Fields, f)
 		}
 		specs = append(specs, spec)
 	}
@@ -0,0 +1,97 @@
+package data
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// parsePercentile parses the percentile of a statistic like p95 or p99.9.
+// It returns false unless stat is p followed by a decimal number between 0
+// and 100, so that forms such as pNaN, pInf or p1e2 are rejected.
+func parsePercentile(stat string) (float64, bool) {
+	if len(stat) < 2 || stat[0] != 'p' || strings.Trim(stat[1:], "0123456789.") != "" {
+		return 0, false
+	}
+	p, err := strconv.ParseFloat(stat[1:], 64)
+	if err != nil || p < 0 || p > 100 {
+		return 0, false
+	}
+	return p, true
+}
+
+// statLocked adds value to the window of f and returns the statistic of f
+// over its window. Gaps are ignored by the statistic but still plotted as
+// gaps.
+func (p *Points) statLocked(f Field, value float64) float64 {
+	if p.windows == nil {
+		p.windows = map[string][]float64{}
+		p.ewma = map[string]float64{}
+	}
+	if f.Stat == "ewma" {
+		if math.IsNaN(value) {
+			return value
+		}
+		last, found := p.ewma[f.ID]
+		if found {
+			alpha := 2 / (float64(f.Window) + 1)
+			value = alpha*value + (1-alpha)*last
+		}
+		p.ewma[f.ID] = value
+		return value
+	}
+	w := append(p.windows[f.ID], value)
+	if len(w) > f.Window {
+		w = w[len(w)-f.Window:]
+	}
+	p.windows[f.ID] = w
+	if math.IsNaN(value) {
+		return value
+	}
+	values := make([]float64, 0, len(w))
+	for _, v := range w {
+		if !math.IsNaN(v) {
+			values = append(values, v)
+		}
+	}
+	return statistic(f.Stat, f.Percentile, values)
+}
+
+// statistic computes stat of values: avg, min, max or p for the percentile
+// p. Values must not be empty.
+func statistic(stat string, p float64, values []float64) float64 {
+	switch stat {
+	case "avg":
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+		return sum / float64(len(values))
+	case "min":
+		min := values[0]
+		for _, v := range values[1:] {
+			min = math.Min(min, v)
+		}
+		return min
+	case "max":
+		max := values[0]
+		for _, v := range values[1:] {
+			max = math.Max(max, v)
+		}
+		return max
+	}
+	return percentile(values, p)
+}
+
+// percentile returns the p-th percentile of values, interpolating between
+// the closest ranks.
+func percentile(values []float64, p float64) float64 {
+	sort.Float64s(values)
+	rank := p / 100 * float64(len(values)-1)
+	i := int(rank)
+	if i >= len(values)-1 {
+		return values[len(values)-1]
+	}
+	return values[i] + (rank-float64(i))*(values[i+1]-values[i])
+}
@@ -0,0 +1,92 @@
+package data
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// normalize returns v as the kind of values held by the field f, so that a
+// field is plotted as a single series whatever the JSON type of its samples:
+// strings holding a number (eg: "200") are numbers in a numeric field, while
+// numbers are states named after their canonical form in a state field.
+func (p *Points) normalize(f Field, v interface{}) interface{} {
+	var n float64
+	switch v := v.(type) {
+	case float64:
+		n = v
+	case string:
+		var err error
+		if n, err = strconv.ParseFloat(strings.TrimSpace(v), 64); err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+			return v
+		}
+	default:
+		return v
+	}
+	p.mu.Lock()
+	isState := p.states[f.ID] != nil
+	p.mu.Unlock()
+	if isState {
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	}
+	return n
+}
+
+// stateOf returns the state held by v if it is a boolean or a string.
+func stateOf(v interface{}) (state string, ok bool) {
+	switch v := v.(type) {
+	case bool:
+		return strconv.FormatBool(v), true
+	case string:
+		return v, true
+	}
+	return "", false
+}
+
+// pushState appends state taken at t to the series of f, which becomes a
+// state field. The values of a state field are the index of their state in
+// the names returned by States.
+func (p *Points) pushState(f Field, t time.Time, state string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.states[f.ID] == nil {
+		// Numbers received before the first state become states.
+		_, d := p.getLocked(f.ID)
+		names := []string{}
+		if p.states == nil {
+			p.states = map[string][]string{}
+		}
+		p.states[f.ID] = names
+		values := make([]float64, len(d))
+		for i, v := range d {
+			values[i] = v
+			if !math.IsNaN(v) {
+				values[i] = p.stateLocked(f.ID, strconv.FormatFloat(v, 'f', -1, 64))
+			}
+		}
+		p.points[f.ID] = values
+	}
+	p.appendLocked(f.ID, t, p.stateLocked(f.ID, state))
+}
+
+// stateLocked returns the index of state in the states of the field id,
+// adding it if new.
+func (p *Points) stateLocked(id, state string) float64 {
+	names := p.states[id]
+	for i, name := range names {
+		if name == state {
+			return float64(i)
+		}
+	}
+	p.states[id] = append(names, state)
+	return float64(len(names))
+}
+
+// States returns the names of the states of the field id, indexed by the
+// values of its series, or nil if the field holds numbers.
+func (p *Points) States(id string) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.states[id]
+}
@@ -1,31 +1,52 @@
 package data
 
 import (
-	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
 	"os"
 
 	"github.com/elgs/gojq"
 )
 
 type stdin struct {
-	scan *bufio.Scanner
+	f   *os.File
+	dec *json.Decoder
+	// pending holds the samples of an array not returned yet.
+	pending []interface{}
 }
 
-// FromStdin reads data from stdin as one JSON object per line.
+// FromStdin reads data from stdin as a stream of JSON objects. Objects may be
+// on one line each, pretty-printed or concatenated, and arrays of objects are
+// read as one sample per item.
 func FromStdin(size int) *Points {
+	f := openStdin()
 	return &Points{
 		Size:   size,
-		Source: stdin{bufio.NewScanner(os.Stdin)},
+		Source: &stdin{f: f, dec: json.NewDecoder(f)},
 	}
 }
 
-func (s stdin) Get() (*gojq.JQ, error) {
-	if s.scan.Scan() {
-		return gojq.NewStringQuery(s.scan.Text())
+func (s *stdin) Get() (*gojq.JQ, error) {
+	for len(s.pending) == 0 {
+		var v interface{}
+		if err := s.dec.Decode(&v); err != nil {
+			if err == io.EOF || errors.Is(err, os.ErrClosed) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		if items, ok := v.([]interface{}); ok {
+			s.pending = items
+			continue
+		}
+		return gojq.NewQuery(v), nil
 	}
-	return nil, s.scan.Err()
+	v := s.pending[0]
+	s.pending = s.pending[1:]
+	return gojq.NewQuery(v), nil
 }
 
-func (s stdin) Close() error {
-	return nil
+func (s *stdin) Close() error {
+	return closeStdin(s.f)
 }
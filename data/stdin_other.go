@@ -0,0 +1,14 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package data
+
+import "os"
+
+func openStdin() *os.File {
+	return os.Stdin
+}
+
+func closeStdin(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,26 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package data
+
+import (
+	"os"
+	"syscall"
+)
+
+// openStdin returns stdin in non-blocking mode, so that a read waiting for
+// data is interrupted by closeStdin. Reads of os.Stdin cannot be interrupted
+// when it is a pipe, which is in blocking mode.
+func openStdin() *os.File {
+	if err := syscall.SetNonblock(syscall.Stdin, true); err != nil {
+		return os.Stdin
+	}
+	return os.NewFile(uintptr(syscall.Stdin), "/dev/stdin")
+}
+
+// closeStdin puts stdin back in blocking mode for the processes sharing it
+// and closes f, interrupting its pending read.
+func closeStdin(f *os.File) error {
+	syscall.SetNonblock(syscall.Stdin, false)
+	return f.Close()
+}
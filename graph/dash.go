@@ -1,37 +1,85 @@
 package graph
 
 import (
+	"fmt"
 	"image"
 	"image/draw"
 	"image/png"
 	"io"
+	"strings"
 
 	"github.com/rs/jplot/data"
 	chart "github.com/wcharczuk/go-chart/v2"
 )
 
+// Dash is a set of graphs stacked vertically.
 type Dash struct {
 	Specs []data.Spec
 	Data  *data.Points
+	View  View
 }
 
 // Render generates a PNG with all graphs stacked.
 func (d Dash) Render(w io.Writer, width, height int) error {
+	img, err := d.Image(width, height)
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, img)
+}
+
+// Image generates an image with all graphs stacked on a transparent
+// background.
+func (d Dash) Image(width, height int) (*image.RGBA, error) {
 	graphs := make([]chart.Chart, 0, len(d.Specs))
-	for _, spec := range d.Specs {
-		graphs = append(graphs, New(spec, d.Data, width, height/len(d.Specs)))
+	heights := d.heights(height)
+	for i, spec := range d.Specs {
+		graphs = append(graphs, New(spec, d.Data, d.View, width, heights[i]))
+	}
+	if msg := d.Status(); msg != "" && len(graphs) > 0 {
+		graphs[0].Elements = append(graphs[0].Elements, status(msg))
 	}
 	canvas := image.NewRGBA(image.Rectangle{image.Point{0, 0}, image.Point{width, height}})
 	var top int
 	for _, graph := range graphs {
 		iw := &chart.ImageWriter{}
 		if err := graph.Render(chart.PNG, iw); err != nil {
-			return err
+			return nil, err
 		}
 		img, _ := iw.Image()
 		r := image.Rectangle{image.Point{0, top}, image.Point{width, top + graph.Height}}
 		top += graph.Height
 		draw.Draw(canvas, r, img, image.Point{0, 0}, draw.Src)
 	}
-	return png.Encode(w, canvas)
+	return canvas, nil
+}
+
+// Status returns the message displayed over the first graph, if any.
+func (d Dash) Status() string {
+	var msgs []string
+	if d.View.Paused() {
+		msgs = append(msgs, "paused at "+d.View.End.Format("15:04:05"))
+	}
+	if since, err := d.Data.Down(); err != nil {
+		msgs = append(msgs, fmt.Sprintf("source down since %s: %v", since.Format("15:04:05"), err))
+	}
+	return strings.Join(msgs, " | ")
+}
+
+// heights splits total between graphs following the Height of their spec.
+func (d Dash) heights(total int) []int {
+	weights := make([]int, len(d.Specs))
+	var sum int
+	for i, spec := range d.Specs {
+		weights[i] = spec.Height
+		if weights[i] <= 0 {
+			weights[i] = 1
+		}
+		sum += weights[i]
+	}
+	heights := make([]int, len(d.Specs))
+	for i, w := range weights {
+		heights[i] = total * w / sum
+	}
+	return heights
 }
@@ -3,6 +3,8 @@ package graph
 import (
 	"fmt"
 	"math"
+	"strings"
+	"time"
 
 	humanize "github.com/dustin/go-humanize"
 	"github.com/rs/jplot/data"
@@ -18,35 +20,161 @@ func init() {
 	chart.DefaultAnnotationFillColor = chart.ColorBlack.WithAlpha(200)
 }
 
-// New generate a line graph with series.
-func New(spec data.Spec, dp *data.Points, width, height int) chart.Chart {
+// New generate a line graph with series within view. Fields holding states
+// are drawn as bands under the graph, or filling it when no field holds
+// numbers.
+func New(spec data.Spec, dp *data.Points, view View, width, height int) chart.Chart {
 	series := []chart.Series{}
 	markers := []chart.GridLine{}
-	for _, f := range spec.Fields {
-		vals := dp.Get(f.ID)
+	for _, f := range dp.Fields(spec) {
+		if dp.States(f.ID) != nil {
+			continue
+		}
+		times, vals := view.series(dp, f.ID)
+		if view.Hidden[f.ID] {
+			if !f.IsMarker {
+				series = append(series, lineSeries{
+					Name:  fieldLabel(f) + ": hidden",
+					Style: chart.Style{StrokeColor: fieldColor(f, len(series))},
+				})
+			}
+			continue
+		}
 		if f.IsMarker {
 			for i, v := range vals {
 				if v > 0 {
-					markers = append(markers, chart.GridLine{Value: float64(i)})
+					markers = append(markers, chart.GridLine{Value: chart.TimeToFloat64(times[i])})
 				}
 			}
 			continue
 		}
-		series = append(series, chart.ContinuousSeries{
-			Name:    fmt.Sprintf("%s: %s", f.Name, siValueFormater(vals[len(vals)-1])),
+		last := math.NaN()
+		if len(vals) > 0 {
+			last = vals[len(vals)-1]
+		}
+		xvalues := make([]float64, len(times))
+		for i, t := range times {
+			xvalues[i] = chart.TimeToFloat64(t)
+		}
+		series = append(series, lineSeries{
+			Name:    fieldLegend(f, dp, siValueFormater(last)),
+			Style:   chart.Style{StrokeColor: fieldColor(f, len(series))},
+			XValues: xvalues,
 			YValues: vals,
 		})
 	}
-	return newChart(series, markers, width, height)
+	rows := stateRows(spec, dp, view)
+	var xmin, xmax float64 = math.MaxFloat64, -math.MaxFloat64
+	for _, s := range series {
+		xmin, xmax = minMax(s.(lineSeries).XValues, xmin, xmax)
+	}
+	for _, row := range rows {
+		xmin, xmax = minMax(row.XValues, xmin, xmax)
+	}
+	if xmin > xmax {
+		// No data received yet.
+		xmin = chart.TimeToFloat64(time.Now())
+		xmax = xmin
+	}
+	if xmin == xmax {
+		// Show at least a second to avoid a zero width range.
+		xmin -= float64(time.Second)
+	}
+	numeric := len(series) > 0
+	if !numeric {
+		// go-chart requires a series.
+		series = append(series, lineSeries{})
+	}
+	graph := newChart(series, markers, xmin, xmax, width, height)
+	switch {
+	case len(rows) == 0:
+	case numeric:
+		bands := len(rows) * stateRowHeight
+		if bands > height/2 {
+			bands = height / 2
+		}
+		graph.Background.Padding.Bottom += bands
+		graph.Elements = append(graph.Elements, stateBands(rows, xmin, xmax, func(cb chart.Box) chart.Box {
+			return chart.Box{Top: height - bands, Left: cb.Left, Right: cb.Right, Bottom: height}
+		}))
+	default:
+		graph.YAxis.Style = chart.Hidden()
+		graph.Elements = []chart.Renderable{stateBands(rows, xmin, xmax, func(cb chart.Box) chart.Box {
+			return cb
+		})}
+	}
+	if spec.Title != "" {
+		graph.Title = spec.Title
+		graph.TitleStyle = chart.Style{FontSize: 10}
+	}
+	return graph
+}
+
+// fieldLabel returns the name of f in the legend.
+func fieldLabel(f data.Field) string {
+	if f.Label != "" {
+		return f.Label
+	}
+	if f.Source != "" {
+		return f.Source + "@" + f.Name
+	}
+	return f.Name
+}
+
+// fieldLegend returns the legend of f with its formatted last value, and the
+// number of samples which could not be read if any.
+func fieldLegend(f data.Field, dp *data.Points, last string) string {
+	legend := fmt.Sprintf("%s: %s", fieldLabel(f), last)
+	switch n := dp.Errors(f.ID); {
+	case n == 1:
+		legend = strings.TrimSpace(legend) + " (1 error)"
+	case n > 1:
+		legend = fmt.Sprintf("%s (%d errors)", strings.TrimSpace(legend), n)
+	}
+	return legend
+}
+
+// fieldColor returns the color of f, the i-th line of its graph.
+func fieldColor(f data.Field, i int) drawing.Color {
+	if f.Color != "" {
+		return drawing.ColorFromHex(strings.TrimPrefix(f.Color, "#"))
+	}
+	return chart.GetAlternateColor(i + 4)
+}
+
+// Color returns the hexadecimal color (eg: #ff0000) of f, the i-th line of its
+// graph, for renderers other than this package.
+func Color(f data.Field, i int) string {
+	return hexColor(fieldColor(f, i))
+}
+
+func hexColor(c drawing.Color) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// Legend returns the legend of f with the last value displayed by d.
+func (d Dash) Legend(f data.Field) string {
+	_, vals := d.Series(f)
+	if names := d.Data.States(f.ID); names != nil {
+		row := stateRow{Names: names, YValues: vals}
+		return fieldLegend(f, d.Data, row.name(len(vals)-1))
+	}
+	last := math.NaN()
+	if len(vals) > 0 {
+		last = vals[len(vals)-1]
+	}
+	return strings.TrimSpace(fieldLegend(f, d.Data, siValueFormater(last)))
 }
 
-func newChart(series []chart.Series, markers []chart.GridLine, width, height int) chart.Chart {
+func newChart(series []chart.Series, markers []chart.GridLine, xmin, xmax float64, width, height int) chart.Chart {
 	var min, max float64 = math.MaxFloat64, -math.MaxFloat64
 	for i, s := range series {
-		if s, ok := s.(chart.ContinuousSeries); ok {
+		if s, ok := s.(lineSeries); ok {
 			min, max = minMax(s.YValues, min, max)
-			s.XValues = chart.LinearRange(0, float64(len(s.YValues)-1))
-			c := chart.GetAlternateColor(i + 4)
+			c := s.Style.StrokeColor
+			if c.IsZero() {
+				c = chart.GetAlternateColor(i + 4)
+			}
 			s.Style = chart.Style{
 				Hidden:      false,
 				StrokeWidth: 2,
@@ -55,12 +183,19 @@ func newChart(series []chart.Series, markers []chart.GridLine, width, height int
 				FontSize:    9,
 			}
 			series[i] = s
-			last := chart.LastValueAnnotationSeries(s, siValueFormater)
-			last.Style.FillColor = c
-			last.Style.FontColor = textColor(c)
-			last.Style.FontSize = 9
-			last.Style.Padding = chart.NewBox(2, 2, 2, 2)
-			series = append(series, last)
+			if x, y, ok := s.last(); ok {
+				last := chart.AnnotationSeries{
+					Style: s.Style,
+					Annotations: []chart.Value2{
+						{XValue: x, YValue: y, Label: siValueFormater(y)},
+					},
+				}
+				last.Style.FillColor = c
+				last.Style.FontColor = textColor(c)
+				last.Style.FontSize = 9
+				last.Style.Padding = chart.NewBox(2, 2, 2, 2)
+				series = append(series, last)
+			}
 		}
 	}
 	graph := chart.Chart{
@@ -75,6 +210,10 @@ func newChart(series []chart.Series, markers []chart.GridLine, width, height int
 		},
 		Series: series,
 	}
+	if min > max {
+		// No value to plot, only gaps.
+		min, max = 0, 0
+	}
 	if min == max {
 		// By default, go-chart will fail to render a flat line as the range will be NaN.
 		// Define a manual range in such case.
@@ -83,23 +222,27 @@ func newChart(series []chart.Series, markers []chart.GridLine, width, height int
 			Min: min - 0.05,
 			Max: max + 0.05,
 		}
+	} else {
+		// Series with gaps do not provide their values to go-chart, compute
+		// the range the way it would.
+		roundTo := chart.GetRoundToForDelta(max - min)
+		graph.YAxis.Range = &chart.ContinuousRange{
+			Min: chart.RoundDown(min, roundTo),
+			Max: chart.RoundUp(max, roundTo),
+		}
+	}
+	graph.XAxis = chart.XAxis{
+		Style: chart.Shown(),
+		Ticks: timeTicks(chart.TimeFromFloat64(xmin), chart.TimeFromFloat64(xmax), width/100),
 	}
 	if len(markers) > 0 {
-		graph.Background.Padding.Bottom = 0 // compensate transparent tick space
-		graph.XAxis = chart.XAxis{
-			Style: chart.Shown(),
-			TickStyle: chart.Style{
-				StrokeColor: chart.ColorTransparent,
-			},
-			TickPosition: 10, // hide text with non-existing position
-			GridMajorStyle: chart.Style{
-				Hidden:          false,
-				StrokeColor:     chart.ColorAlternateGray.WithAlpha(100),
-				StrokeWidth:     2.0,
-				StrokeDashArray: []float64{2.0, 2.0},
-			},
-			GridLines: markers,
+		graph.XAxis.GridMajorStyle = chart.Style{
+			Hidden:          false,
+			StrokeColor:     chart.ColorAlternateGray.WithAlpha(100),
+			StrokeWidth:     2.0,
+			StrokeDashArray: []float64{2.0, 2.0},
 		}
+		graph.XAxis.GridLines = markers
 	}
 	graph.Elements = []chart.Renderable{
 		legend(&graph, chart.Style{
@@ -114,6 +257,9 @@ func newChart(series []chart.Series, markers []chart.GridLine, width, height int
 func minMax(values []float64, curMin, curMax float64) (min, max float64) {
 	min, max = curMin, curMax
 	for _, value := range values {
+		if math.IsNaN(value) {
+			continue
+		}
 		if value < min {
 			min = value
 		}
@@ -125,6 +271,9 @@ func minMax(values []float64, curMin, curMax float64) (min, max float64) {
 }
 
 func siValueFormater(v interface{}) string {
+	if math.IsNaN(v.(float64)) {
+		return "-"
+	}
 	value, prefix := humanize.ComputeSI(v.(float64))
 	value = float64(int(value*100)) / 100
 	return humanize.Ftoa(value) + " " + prefix
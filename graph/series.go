@@ -0,0 +1,63 @@
+package graph
+
+import (
+	"math"
+
+	chart "github.com/wcharczuk/go-chart/v2"
+)
+
+// lineSeries is a continuous series broken wherever its value is NaN.
+//
+// It intentionally does not implement chart.ValuesProvider so NaN values do not
+// end up in the ranges computed by go-chart; ranges must be set on the axes.
+type lineSeries struct {
+	Name    string
+	Style   chart.Style
+	XValues []float64
+	YValues []float64
+}
+
+func (s lineSeries) GetName() string {
+	return s.Name
+}
+
+func (s lineSeries) GetStyle() chart.Style {
+	return s.Style
+}
+
+func (s lineSeries) GetYAxis() chart.YAxisType {
+	return chart.YAxisPrimary
+}
+
+func (s lineSeries) Validate() error {
+	return nil
+}
+
+// Render draws each segment of consecutive values.
+func (s lineSeries) Render(r chart.Renderer, canvasBox chart.Box, xrange, yrange chart.Range, defaults chart.Style) {
+	style := s.Style.InheritFrom(defaults)
+	start := -1
+	for i := 0; i <= len(s.YValues); i++ {
+		if i < len(s.YValues) && !math.IsNaN(s.YValues[i]) {
+			if start == -1 {
+				start = i
+			}
+			continue
+		}
+		if start != -1 {
+			chart.Draw.LineSeries(r, canvasBox, xrange, yrange, style, chart.ContinuousSeries{
+				XValues: s.XValues[start:i],
+				YValues: s.YValues[start:i],
+			})
+			start = -1
+		}
+	}
+}
+
+// last returns the last value of the series if it is not a gap.
+func (s lineSeries) last() (x, y float64, ok bool) {
+	if n := len(s.YValues); n > 0 && !math.IsNaN(s.YValues[n-1]) {
+		return s.XValues[n-1], s.YValues[n-1], true
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,172 @@
+package graph
+
+import (
+	"math"
+	"sort"
+
+	"github.com/rs/jplot/data"
+	chart "github.com/wcharczuk/go-chart/v2"
+	"github.com/wcharczuk/go-chart/v2/drawing"
+)
+
+// stateRowHeight is the height in pixels of the band of a state field.
+const stateRowHeight = 16
+
+// stateColors are the colors of states other than true and false, which are
+// green and red.
+var stateColors = []drawing.Color{
+	chart.ColorBlue,
+	chart.ColorOrange,
+	chart.ColorCyan,
+	chart.ColorAlternateYellow,
+	chart.ColorAlternateBlue,
+	chart.ColorAlternateGray,
+}
+
+// stateRow is the timeline of a field holding booleans or strings. Its values
+// are the index of their state in Names, or NaN for gaps.
+type stateRow struct {
+	Label   string
+	Names   []string
+	XValues []float64
+	YValues []float64
+}
+
+// stateRows returns the timelines of the state fields of spec within view.
+func stateRows(spec data.Spec, dp *data.Points, view View) []stateRow {
+	var rows []stateRow
+	for _, f := range dp.Fields(spec) {
+		if dp.States(f.ID) == nil {
+			continue
+		}
+		if view.Hidden[f.ID] {
+			rows = append(rows, stateRow{Label: fieldLabel(f) + ": hidden"})
+			continue
+		}
+		// States are only added, so the names read after the values cover
+		// all of them.
+		times, vals := view.series(dp, f.ID)
+		row := stateRow{
+			Names:   dp.States(f.ID),
+			XValues: make([]float64, len(times)),
+			YValues: vals,
+		}
+		for i, t := range times {
+			row.XValues[i] = chart.TimeToFloat64(t)
+		}
+		row.Label = fieldLegend(f, dp, row.name(len(vals)-1))
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// name returns the name of the i-th state of the row, or "-" for a gap.
+func (s stateRow) name(i int) string {
+	if !s.valid(i) {
+		return "-"
+	}
+	return s.Names[int(s.YValues[i])]
+}
+
+// valid returns true if the i-th value of the row is the index of a state. A
+// field turned into a state field while being read may still hold numbers.
+func (s stateRow) valid(i int) bool {
+	if i < 0 || i >= len(s.YValues) || math.IsNaN(s.YValues[i]) {
+		return false
+	}
+	n := int(s.YValues[i])
+	return n >= 0 && n < len(s.Names)
+}
+
+// at returns the index of the last value of the row at or before x, or -1.
+func (s stateRow) at(x float64) int {
+	return sort.Search(len(s.XValues), func(i int) bool { return s.XValues[i] > x }) - 1
+}
+
+// color returns the color of the i-th value of the row.
+func (s stateRow) color(i int) drawing.Color {
+	if !s.valid(i) {
+		return chart.DefaultTextColor
+	}
+	return stateColor(s.Names, int(s.YValues[i]))
+}
+
+// stateColor returns the color of the i-th state of names.
+func stateColor(names []string, i int) drawing.Color {
+	switch names[i] {
+	case "true":
+		return chart.ColorGreen
+	case "false":
+		return chart.ColorRed
+	}
+	return stateColors[i%len(stateColors)]
+}
+
+// StateColors returns the hexadecimal colors of the states of a field, such
+// as returned by data.Points.States.
+func StateColors(names []string) []string {
+	colors := make([]string, len(names))
+	for i := range names {
+		colors[i] = hexColor(stateColor(names, i))
+	}
+	return colors
+}
+
+// stateBands renders rows as stacked bands within the box returned by box,
+// each value being drawn as a segment of the color of its state lasting until
+// the next value. The state names are written in segments large enough.
+func stateBands(rows []stateRow, xmin, xmax float64, box func(cb chart.Box) chart.Box) chart.Renderable {
+	return func(r chart.Renderer, cb chart.Box, chartDefaults chart.Style) {
+		b := box(cb)
+		xpos := func(x float64) int {
+			return b.Left + int(math.Round((x-xmin)/(xmax-xmin)*float64(b.Width())))
+		}
+		textStyle := chart.Style{
+			FontColor: chart.ColorWhite,
+			FontSize:  8.0,
+		}.InheritFrom(chartDefaults)
+		labelStyle := chart.Style{
+			FillColor:   drawing.Color{A: 100},
+			StrokeColor: chart.ColorTransparent,
+		}
+		height := float64(b.Height()) / float64(len(rows))
+		for n, row := range rows {
+			top := b.Top + int(float64(n)*height)
+			bottom := b.Top + int(float64(n+1)*height) - 1
+			// Consecutive values of the same state are drawn as one segment.
+			for i := 0; i < len(row.YValues); {
+				j := i + 1
+				for j < len(row.YValues) && row.name(j) == row.name(i) {
+					j++
+				}
+				if !row.valid(i) {
+					i = j
+					continue
+				}
+				seg := chart.Box{Top: top, Bottom: bottom, Left: xpos(row.XValues[i]), Right: b.Right}
+				if j < len(row.XValues) {
+					seg.Right = xpos(row.XValues[j])
+				}
+				if seg.Left < b.Left {
+					seg.Left = b.Left
+				}
+				c := row.color(i)
+				chart.Draw.Box(r, seg, chart.Style{FillColor: c.WithAlpha(200), StrokeColor: chart.ColorTransparent})
+				style := textStyle
+				style.FontColor = textColor(c)
+				style.GetTextOptions().WriteToRenderer(r)
+				if tb := r.MeasureText(row.name(i)); tb.Width()+4 < seg.Width() && tb.Height() < seg.Height() {
+					r.Text(row.name(i), seg.Right-tb.Width()-2, bottom-(seg.Height()-tb.Height())/2)
+				}
+				i = j
+			}
+			// The label is written on the left of the band, over the segments.
+			textStyle.GetTextOptions().WriteToRenderer(r)
+			tb := r.MeasureText(row.Label)
+			label := chart.Box{Top: top, Bottom: bottom, Left: b.Left, Right: b.Left + tb.Width() + 6}
+			chart.Draw.Box(r, label, labelStyle)
+			textStyle.GetTextOptions().WriteToRenderer(r)
+			r.Text(row.Label, label.Left+3, bottom-(label.Height()-tb.Height())/2)
+		}
+	}
+}
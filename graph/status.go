@@ -0,0 +1,32 @@
+package graph
+
+import (
+	chart "github.com/wcharczuk/go-chart/v2"
+	"github.com/wcharczuk/go-chart/v2/drawing"
+)
+
+// status renders text in a red box in the top right corner of the canvas.
+func status(text string) chart.Renderable {
+	return func(r chart.Renderer, cb chart.Box, chartDefaults chart.Style) {
+		style := chart.Style{
+			FillColor:   drawing.Color{R: 200, G: 40, B: 40, A: 220},
+			FontColor:   chart.ColorWhite,
+			FontSize:    9.0,
+			StrokeColor: chart.ColorTransparent,
+		}.InheritFrom(chartDefaults)
+		padding := 5
+
+		style.GetTextOptions().WriteToRenderer(r)
+		tb := r.MeasureText(text)
+		box := chart.Box{
+			Top:    cb.Top,
+			Right:  cb.Right,
+			Left:   cb.Right - tb.Width() - 2*padding,
+			Bottom: cb.Top + tb.Height() + 2*padding,
+		}
+		chart.Draw.Box(r, box, style)
+
+		style.GetTextOptions().WriteToRenderer(r)
+		r.Text(text, box.Left+padding, box.Bottom-padding)
+	}
+}
@@ -0,0 +1,396 @@
+package graph
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rs/jplot/data"
+	chart "github.com/wcharczuk/go-chart/v2"
+	"github.com/wcharczuk/go-chart/v2/drawing"
+)
+
+// RenderText generates all graphs stacked as text using Unicode Braille
+// characters and ANSI colors, for terminals without graphics support. The
+// output is rows lines of cols characters.
+func (d Dash) RenderText(w io.Writer, cols, rows int) error {
+	if len(d.Specs) == 0 {
+		return nil
+	}
+	lines := make([]string, 0, rows)
+	if msg := d.Status(); msg != "" && rows > 0 {
+		// The status takes the first line, above the legend of the first graph.
+		lines = append(lines, ansiBackground(drawing.Color{R: 200, G: 40, B: 40, A: 255})+
+			ansiForeground(chart.ColorWhite)+padText(" "+msg+" ", cols)+ansiReset)
+	}
+	heights := d.heights(rows - len(lines))
+	for i, spec := range d.Specs {
+		height := heights[i]
+		if i == len(d.Specs)-1 {
+			// Give the rounding remainder to the last graph.
+			height = rows - len(lines)
+		}
+		lines = append(lines, NewText(spec, d.Data, d.View, cols, height)...)
+	}
+	_, err := io.WriteString(w, strings.Join(lines, "\n"))
+	return err
+}
+
+// NewText generates a line graph of spec within view as height lines of text
+// of width characters. The first line is the legend and the last one the time
+// axis.
+func NewText(spec data.Spec, dp *data.Points, view View, width, height int) []string {
+	lines := make([]string, 0, height)
+	var series []lineSeries
+	var markers []float64
+	var legend strings.Builder
+	legendWidth := 0
+	if spec.Title != "" {
+		title := spec.Title + "  "
+		if utf8.RuneCountInString(title) > width {
+			title = padText(title, width)
+		}
+		legend.WriteString(ansiBold + title + ansiReset)
+		legendWidth += utf8.RuneCountInString(title)
+	}
+	rows := stateRows(spec, dp, view)
+	for _, f := range dp.Fields(spec) {
+		if dp.States(f.ID) != nil {
+			continue
+		}
+		if view.Hidden[f.ID] {
+			if !f.IsMarker {
+				entry := fmt.Sprintf("□ %s: hidden  ", fieldLabel(f))
+				if legendWidth+utf8.RuneCountInString(entry) <= width {
+					legend.WriteString(ansiForeground(fieldColor(f, len(series))) + "□" + ansiReset + entry[len("□"):])
+					legendWidth += utf8.RuneCountInString(entry)
+				}
+				series = append(series, lineSeries{})
+			}
+			continue
+		}
+		times, vals := view.series(dp, f.ID)
+		xvalues := make([]float64, len(times))
+		for i, t := range times {
+			xvalues[i] = chart.TimeToFloat64(t)
+		}
+		if f.IsMarker {
+			for i, v := range vals {
+				if v > 0 {
+					markers = append(markers, xvalues[i])
+				}
+			}
+			continue
+		}
+		c := fieldColor(f, len(series))
+		last := math.NaN()
+		if len(vals) > 0 {
+			last = vals[len(vals)-1]
+		}
+		if entry := "■ " + fieldLegend(f, dp, siValueFormater(last)) + "  "; legendWidth+utf8.RuneCountInString(entry) <= width {
+			legend.WriteString(ansiForeground(c) + "■" + ansiReset + entry[len("■"):])
+			legendWidth += utf8.RuneCountInString(entry)
+		}
+		series = append(series, lineSeries{
+			Style:   chart.Style{StrokeColor: c},
+			XValues: xvalues,
+			YValues: vals,
+		})
+	}
+	for _, row := range rows {
+		c := chart.DefaultTextColor
+		if n := len(row.YValues); n > 0 {
+			c = row.color(n - 1)
+		}
+		if entry := "■ " + row.Label + "  "; legendWidth+utf8.RuneCountInString(entry) <= width {
+			legend.WriteString(ansiForeground(c) + "■" + ansiReset + entry[len("■"):])
+			legendWidth += utf8.RuneCountInString(entry)
+		}
+	}
+	lines = append(lines, legend.String()+strings.Repeat(" ", width-legendWidth))
+	if height < 3 {
+		return lines[:height]
+	}
+	if len(rows) > height-3 {
+		// Keep at least a row for the graph.
+		rows = rows[:height-3]
+	}
+
+	var min, max float64 = math.MaxFloat64, -math.MaxFloat64
+	var xmin, xmax float64 = math.MaxFloat64, -math.MaxFloat64
+	for _, s := range series {
+		min, max = minMax(s.YValues, min, max)
+		xmin, xmax = minMax(s.XValues, xmin, xmax)
+	}
+	for _, row := range rows {
+		xmin, xmax = minMax(row.XValues, xmin, xmax)
+	}
+	if min > max {
+		min, max = 0, 0
+	}
+	if min == max {
+		min, max = min-0.05, max+0.05
+	}
+	if xmin > xmax {
+		xmin = chart.TimeToFloat64(time.Now())
+		xmax = xmin
+	}
+	if xmin == xmax {
+		xmin -= float64(time.Second)
+	}
+
+	// Right margin holds the Y axis bounds and the last values.
+	type label struct {
+		row  int
+		text string
+		c    drawing.Color
+	}
+	// State fields take a line each under the graph, or share all the lines
+	// when no field holds numbers.
+	graphRows := height - 2 - len(rows)
+	rowOf := func(line int) int { return line - graphRows }
+	var labels []label
+	if len(series) == 0 && len(rows) > 0 {
+		graphRows = 0
+		rowOf = func(line int) int { return line * len(rows) / (height - 2) }
+	} else {
+		labels = []label{
+			{0, siValueFormater(max), chart.DefaultTextColor},
+			{graphRows - 1, siValueFormater(min), chart.DefaultTextColor},
+		}
+	}
+	for _, s := range series {
+		if _, y, ok := s.last(); ok {
+			row := int(math.Round((max - y) / (max - min) * float64(graphRows-1)))
+			labels = append(labels, label{row, siValueFormater(y), s.Style.StrokeColor})
+		}
+	}
+	for line := graphRows; line < height-2; line++ {
+		i := rowOf(line)
+		if line > graphRows && rowOf(line-1) == i {
+			continue
+		}
+		if n := len(rows[i].YValues); rows[i].valid(n - 1) {
+			labels = append(labels, label{line, rows[i].name(n - 1), rows[i].color(n - 1)})
+		}
+	}
+	margin := 0
+	for _, l := range labels {
+		if n := utf8.RuneCountInString(l.text); n > margin {
+			margin = n
+		}
+	}
+	margin += 2
+	cols := width - margin
+	if cols < 2 {
+		return lines
+	}
+
+	c := newBrailleCanvas(cols, graphRows)
+	xpos := func(x float64) int {
+		return int(math.Round((x - xmin) / (xmax - xmin) * float64(c.width()-1)))
+	}
+	ypos := func(y float64) int {
+		return int(math.Round((max - y) / (max - min) * float64(c.height()-1)))
+	}
+	for _, x := range markers {
+		c.marker(xpos(x) / 2)
+	}
+	for _, s := range series {
+		px, py := -1, -1
+		for i, y := range s.YValues {
+			if math.IsNaN(y) {
+				px, py = -1, -1
+				continue
+			}
+			x, y := xpos(s.XValues[i]), ypos(y)
+			if px == -1 {
+				c.dot(x, y, s.Style.StrokeColor)
+			} else {
+				c.line(px, py, x, y, s.Style.StrokeColor)
+			}
+			px, py = x, y
+		}
+	}
+
+	margins := make([]string, height-2)
+	for _, l := range labels {
+		if l.row >= 0 && l.row < len(margins) {
+			margins[l.row] = " " + ansiForeground(l.c) + padText(l.text, margin-1) + ansiReset
+		}
+	}
+	for row := range margins {
+		m := margins[row]
+		if m == "" {
+			m = strings.Repeat(" ", margin)
+		}
+		if row < graphRows {
+			lines = append(lines, c.row(row)+m)
+		} else {
+			lines = append(lines, stateLine(rows[rowOf(row)], cols, xmin, xmax)+m)
+		}
+	}
+
+	axis := []rune(strings.Repeat(" ", cols))
+	for _, t := range timeTicks(chart.TimeFromFloat64(xmin), chart.TimeFromFloat64(xmax), cols/12) {
+		if t.Label == "" {
+			continue
+		}
+		pos := xpos(t.Value)/2 - utf8.RuneCountInString(t.Label)/2
+		if pos < 0 || pos+utf8.RuneCountInString(t.Label) > cols {
+			continue
+		}
+		copy(axis[pos:], []rune(t.Label))
+	}
+	lines = append(lines, ansiForeground(chart.DefaultTextColor)+string(axis)+ansiReset+strings.Repeat(" ", margin))
+	return lines
+}
+
+// stateLine renders the timeline of row as cols cells between xmin and xmax,
+// each cell having the color of the state at its time.
+func stateLine(row stateRow, cols int, xmin, xmax float64) string {
+	var b strings.Builder
+	var cur drawing.Color
+	for col := 0; col < cols; col++ {
+		x := xmin + (xmax-xmin)*(float64(col)+0.5)/float64(cols)
+		i := row.at(x)
+		if !row.valid(i) {
+			b.WriteRune(' ')
+			continue
+		}
+		if color := row.color(i); !color.Equals(cur) {
+			b.WriteString(ansiForeground(color))
+			cur = color
+		}
+		b.WriteRune('█')
+	}
+	b.WriteString(ansiReset)
+	return b.String()
+}
+
+// brailleCanvas is a grid of cells of 2x4 dots each.
+type brailleCanvas struct {
+	cols, rows int
+	dots       []rune
+	colors     []drawing.Color
+	markers    []bool
+}
+
+func newBrailleCanvas(cols, rows int) *brailleCanvas {
+	return &brailleCanvas{
+		cols:    cols,
+		rows:    rows,
+		dots:    make([]rune, cols*rows),
+		colors:  make([]drawing.Color, cols*rows),
+		markers: make([]bool, cols),
+	}
+}
+
+func (c *brailleCanvas) width() int  { return c.cols * 2 }
+func (c *brailleCanvas) height() int { return c.rows * 4 }
+
+// brailleDots maps the position of a dot in a cell to its bit.
+var brailleDots = [4][2]rune{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+func (c *brailleCanvas) dot(x, y int, color drawing.Color) {
+	if x < 0 || y < 0 || x >= c.width() || y >= c.height() {
+		return
+	}
+	i := y/4*c.cols + x/2
+	c.dots[i] |= brailleDots[y%4][x%2]
+	c.colors[i] = color
+}
+
+// line draws a line between two dots using Bresenham's algorithm.
+func (c *brailleCanvas) line(x0, y0, x1, y1 int, color drawing.Color) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	err := dx + dy
+	for {
+		c.dot(x0, y0, color)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			err += dx
+			y0 += sy
+		}
+	}
+}
+
+func (c *brailleCanvas) marker(col int) {
+	if col >= 0 && col < c.cols {
+		c.markers[col] = true
+	}
+}
+
+// row renders a row of cells.
+func (c *brailleCanvas) row(row int) string {
+	var b strings.Builder
+	var cur drawing.Color
+	for col := 0; col < c.cols; col++ {
+		i := row*c.cols + col
+		r, color := ' ', cur
+		switch {
+		case c.dots[i] != 0:
+			r, color = 0x2800+c.dots[i], c.colors[i]
+		case c.markers[col]:
+			r, color = '┊', chart.ColorAlternateGray
+		}
+		if r != ' ' && !color.Equals(cur) {
+			b.WriteString(ansiForeground(color))
+			cur = color
+		}
+		b.WriteRune(r)
+	}
+	b.WriteString(ansiReset)
+	return b.String()
+}
+
+const (
+	ansiReset = "\033[0m"
+	ansiBold  = "\033[1m"
+)
+
+func ansiForeground(c drawing.Color) string {
+	return fmt.Sprintf("\033[38;2;%d;%d;%dm", c.R, c.G, c.B)
+}
+
+func ansiBackground(c drawing.Color) string {
+	return fmt.Sprintf("\033[48;2;%d;%d;%dm", c.R, c.G, c.B)
+}
+
+// padText truncates or pads s with spaces to n characters.
+func padText(s string, n int) string {
+	r := []rune(s)
+	if len(r) > n {
+		return string(r[:n])
+	}
+	return s + strings.Repeat(" ", n-len(r))
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,62 @@
+package graph
+
+import (
+	"time"
+
+	chart "github.com/wcharczuk/go-chart/v2"
+)
+
+// tickSteps are the candidate durations between two time ticks.
+var tickSteps = []time.Duration{
+	100 * time.Millisecond,
+	200 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2 * time.Second,
+	5 * time.Second,
+	10 * time.Second,
+	15 * time.Second,
+	30 * time.Second,
+	time.Minute,
+	2 * time.Minute,
+	5 * time.Minute,
+	10 * time.Minute,
+	15 * time.Minute,
+	30 * time.Minute,
+	time.Hour,
+	2 * time.Hour,
+	6 * time.Hour,
+	12 * time.Hour,
+	24 * time.Hour,
+}
+
+// timeTicks returns at most count ticks between min and max, aligned on a
+// round step so labels are easy to read.
+//
+// Ticks with no label are added at min and max, as go-chart sets the range of
+// an axis with explicit ticks to the extent of those ticks.
+func timeTicks(min, max time.Time, count int) []chart.Tick {
+	span := max.Sub(min)
+	step := tickSteps[len(tickSteps)-1]
+	for _, s := range tickSteps {
+		if count > 0 && span/s <= time.Duration(count) {
+			step = s
+			break
+		}
+	}
+	format := "15:04:05"
+	switch {
+	case step < time.Second:
+		format = "15:04:05.0"
+	case step >= time.Minute:
+		format = "15:04"
+	}
+	ticks := []chart.Tick{{Value: chart.TimeToFloat64(min)}}
+	for t := min.Truncate(step).Add(step); t.Before(max); t = t.Add(step) {
+		ticks = append(ticks, chart.Tick{
+			Value: chart.TimeToFloat64(t),
+			Label: t.Format(format),
+		})
+	}
+	return append(ticks, chart.Tick{Value: chart.TimeToFloat64(max)})
+}
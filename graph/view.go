@@ -0,0 +1,82 @@
+package graph
+
+import (
+	"sort"
+	"time"
+
+	"github.com/rs/jplot/data"
+)
+
+// View selects the part of the stored data displayed by a Dash.
+type View struct {
+	// Steps is the number of most recent values displayed when Span is not
+	// set. All stored values are displayed when zero.
+	Steps int
+	// End is the time of the last value displayed. The view follows the
+	// latest value when zero, and is frozen otherwise.
+	End time.Time
+	// Span is the duration displayed up to End.
+	Span time.Duration
+	// Hidden holds the IDs of the fields not drawn.
+	Hidden map[string]bool
+}
+
+// Paused returns true when the view does not follow the latest values.
+func (v View) Paused() bool {
+	return !v.End.IsZero()
+}
+
+// series returns the values of the field id within the view.
+func (v View) series(dp *data.Points, id string) ([]time.Time, []float64) {
+	times, vals := dp.Series(id)
+	end := len(times)
+	if v.Paused() {
+		end = sort.Search(len(times), func(i int) bool { return times[i].After(v.End) })
+	}
+	start := 0
+	switch {
+	case v.Span > 0:
+		last := v.End
+		if !v.Paused() && end > 0 {
+			last = times[end-1]
+		}
+		from := last.Add(-v.Span)
+		start = sort.Search(end, func(i int) bool { return !times[i].Before(from) })
+	case v.Steps > 0 && end > v.Steps:
+		start = end - v.Steps
+	}
+	return times[start:end], vals[start:end]
+}
+
+// Series returns the values of the field f displayed by d.
+func (d Dash) Series(f data.Field) ([]time.Time, []float64) {
+	return d.View.series(d.Data, f.ID)
+}
+
+// Window returns the time range of the values displayed by d.
+func (d Dash) Window() (start, end time.Time) {
+	return d.bounds(d.View)
+}
+
+// Bounds returns the time range of all the values stored for d.
+func (d Dash) Bounds() (first, last time.Time) {
+	return d.bounds(View{})
+}
+
+func (d Dash) bounds(v View) (first, last time.Time) {
+	for _, spec := range d.Specs {
+		for _, f := range d.Data.Fields(spec) {
+			times, _ := v.series(d.Data, f.ID)
+			if len(times) == 0 {
+				continue
+			}
+			if first.IsZero() || times[0].Before(first) {
+				first = times[0]
+			}
+			if times[len(times)-1].After(last) {
+				last = times[len(times)-1]
+			}
+		}
+	}
+	return first, last
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"time"
+
+	"github.com/rs/jplot/graph"
+	"github.com/rs/jplot/term"
+)
+
+// minSpan is the narrowest time window reachable by zooming in.
+const minSpan = 2 * time.Second
+
+// handleKey updates the view of dash following the key k. It returns false
+// if the key is not bound to any action.
+func handleKey(dash *graph.Dash, k term.Key) bool {
+	v := &dash.View
+	start, end := dash.Window()
+	span := end.Sub(start)
+	if v.Span > 0 {
+		span = v.Span
+	}
+	switch k {
+	case ' ', 'p':
+		// Pause and resume. Values keep being collected while paused.
+		if v.Paused() {
+			v.End = time.Time{}
+		} else if !end.IsZero() {
+			v.End = end
+		}
+	case term.KeyLeft, 'h':
+		if end.IsZero() {
+			return true
+		}
+		first, _ := dash.Bounds()
+		if v.End = end.Add(-span / 4); v.End.Before(first) {
+			v.End = first
+		}
+	case term.KeyRight, 'l':
+		if !v.Paused() {
+			return true
+		}
+		// Follow the latest values again once scrolled back to them.
+		if _, last := dash.Bounds(); !end.Add(span / 4).Before(last) {
+			v.End = time.Time{}
+		} else {
+			v.End = end.Add(span / 4)
+		}
+	case '+', '=', term.KeyUp:
+		if span/2 >= minSpan {
+			v.Span = span / 2
+		}
+	case '-', '_', term.KeyDown:
+		if span > 0 {
+			v.Span = span * 2
+		}
+	case 'r':
+		*v = graph.View{Steps: v.Steps}
+	default:
+		if k < '1' || k > '9' {
+			return false
+		}
+		toggleField(dash, int(k-'1'))
+	}
+	return true
+}
+
+// toggleField hides or shows the n-th line of dash, counting the lines of
+// all graphs in order.
+func toggleField(dash *graph.Dash, n int) {
+	for _, spec := range dash.Specs {
+		for _, f := range dash.Data.Fields(spec) {
+			if f.IsMarker {
+				continue
+			}
+			if n--; n >= 0 {
+				continue
+			}
+			// The map is copied as other copies of dash may be rendering.
+			hidden := map[string]bool{f.ID: !dash.View.Hidden[f.ID]}
+			for id, h := range dash.View.Hidden {
+				if id != f.ID {
+					hidden[id] = h
+				}
+			}
+			dash.View.Hidden = hidden
+			return
+		}
+	}
+}
@@ -3,6 +3,7 @@ package main // import "github.com/rs/jplot"
 import (
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"strings"
@@ -20,51 +21,344 @@ func main() {
 	flag.Usage = func() {
 		out := os.Stderr
 		fmt.Fprintln(out, "Usage: jplot [OPTIONS] FIELD_SPEC [FIELD_SPEC...]:")
+		fmt.Fprintln(out, "       jplot --config FILE [OPTIONS] [FIELD_SPEC...]:")
 		fmt.Fprintln(out, "")
 		fmt.Fprintln(out, "OPTIONS:")
 		flag.PrintDefaults()
 		fmt.Fprintln(out, "")
-		fmt.Fprintln(out, "FIELD_SPEC: [<option>[,<option>...]:]path")
+		fmt.Fprintln(out, "FIELD_SPEC: [<option>[,<option>...]:][<source>@]path")
 		fmt.Fprintln(out, "  option:")
 		fmt.Fprintln(out, "    - counter: Computes the difference with the last value. The value must increase monotonically.")
+		fmt.Fprintln(out, "    - rate: Computes the per-second increase since the last value using the actual elapsed time.")
 		fmt.Fprintln(out, "    - marker: When the value is none-zero, a vertical line is drawn.")
+		fmt.Fprintln(out, "    - avg[=N], ewma[=N], min[=N], max[=N]: Plots the moving average, exponentially weighted moving average,")
+		fmt.Fprintln(out, "      rolling minimum or rolling maximum of the last N values (10 by default).")
+		fmt.Fprintln(out, "    - pXX[=N]: Plots the XX-th percentile of the last N values (10 by default), eg: p95=30.")
+		fmt.Fprintln(out, "  source:")
+		fmt.Fprintln(out, "    Name of the --source the path is read from. The --url or stdin is read if not specified.")
 		fmt.Fprintln(out, "  path:")
-		fmt.Fprintln(out, "    JSON field path (eg: field.sub-field).")
+		fmt.Fprintln(out, "    JSON field path (eg: field.sub-field) or Prometheus series (eg: http_requests_total{code=\"200\"}).")
+		fmt.Fprintln(out, "    Segments can be globs (eg: requests.*.count) or regexps between slashes (eg: memstats./^Heap/)")
+		fmt.Fprintln(out, "    matching several keys, each of them being plotted.")
+		fmt.Fprintln(out, "    Can also be an arithmetic expression with +, -, *, / between parentheses (eg: (errors/requests*100)).")
+		fmt.Fprintln(out, "    Arrays are plotted with a line per item, or reduced with count, sum, avg, min, max or pXX (eg: p99(memstats.PauseNs)).")
+		fmt.Fprintln(out, "    Fields holding booleans or strings (eg: healthy, state) are drawn as a band with a color per state.")
+		fmt.Fprintln(out, "")
+		fmt.Fprintln(out, "AGGREGATES: with --aggregate, paths reference statistics of the events")
+		fmt.Fprintln(out, "  count              Number of events.")
+		fmt.Fprintln(out, "  path.count         Number of events with a numeric value at path.")
+		fmt.Fprintln(out, "  path.sum, path.avg, path.min, path.max")
+		fmt.Fprintln(out, "                     Sum, average, minimum and maximum of the values at path.")
+		fmt.Fprintln(out, "  path.pXX           XX-th percentile of the values at path (eg: latency.p95).")
+		fmt.Fprintln(out, "  path.hist.value    Number of events with value at path (eg: code.hist.200, or code.hist.* for all).")
+		fmt.Fprintln(out, "")
+		fmt.Fprintln(out, "KEYS:")
+		fmt.Fprintln(out, "  space, p     Pause or resume the display, values keep being collected.")
+		fmt.Fprintln(out, "  left, h      Scroll back in time.")
+		fmt.Fprintln(out, "  right, l     Scroll forward in time, resume once the latest values are reached.")
+		fmt.Fprintln(out, "  +, up        Zoom in.")
+		fmt.Fprintln(out, "  -, down      Zoom out.")
+		fmt.Fprintln(out, "  1-9          Hide or show the n-th line, counting the lines of all graphs in order.")
+		fmt.Fprintln(out, "  r            Reset the view.")
+		fmt.Fprintln(out, "  q            Quit.")
 	}
 	url := flag.String("url", "", "URL to fetch every second. Read JSON objects from stdin if not specified.")
+	command := flag.String("exec", "", "Shell command to run every interval instead of fetching a URL, its output being parsed as a JSON object."+
+		" A run failing or lasting more than the timeout is recorded as a gap.")
+	file := flag.String("file", "", "File to follow like tail -F, as one JSON object per line. Rotated and truncated files are followed.")
+	backfill := flag.Bool("backfill", false, "With file, read the lines already in the file instead of only the ones appended.")
+	replay := flag.String("replay", "", "Session file written with record to play back instead of reading a source.")
+	replaySpeed := flag.Float64("replay-speed", 1, "With replay, speed factor of the playback (eg: 10 for 10 times faster), 0 to replay at once.")
+	record := flag.String("record", "", "File to write every sample received to, along with its time, to replay the session later.")
+	sources := sourceFlag{}
+	flag.Var(sources, "source", "Named source as name=URL to fetch every interval, name=exec:command to run a command"+
+		" or name=file:path to follow a file, can be repeated. Fields read it when their path is prefixed with the name"+
+		" and @ (eg: canary@memstats.HeapInuse).")
+	format := flag.String("format", "json", "Format of the data: json or prometheus for url, json, csv or tsv for stdin and file, json for exec and replay.")
 	interval := flag.Duration("interval", time.Second, "When url is provided, defines the interval between fetches."+
-		" Note that counter fields are computed based on this interval.")
+		" With aggregate, defines the duration over which events are aggregated."+
+		" Note that counter fields are computed based on this interval, use rate fields to get per-second values.")
+	timeout := flag.Duration("timeout", 0, "When url is provided, maximum duration of a request. Defaults to the interval.")
+	method := flag.String("method", "", "When url is provided, HTTP method of the requests. Defaults to GET, or POST when data is set.")
+	body := flag.String("data", "", "When url is provided, body sent with the requests as JSON unless a Content-Type header is set."+
+		" Read from a file when prefixed with @ (eg: @query.json).")
+	headers := headerFlag{}
+	flag.Var(headers, "header", "When url is provided, header added to the requests as \"Name: value\", can be repeated.")
+	bearerToken := flag.String("bearer-token", "", "When url is provided, token sent in the Authorization header."+
+		" Read from a file when prefixed with @.")
+	user := flag.String("user", "", "When url is provided, user:password sent using basic authentication.")
+	cert := flag.String("cert", "", "When url is provided, PEM client certificate file, including the key if key is not set.")
+	key := flag.String("key", "", "When url is provided, PEM private key file of the client certificate.")
+	cacert := flag.String("cacert", "", "When url is provided, PEM bundle of the certificate authorities used to verify servers.")
+	retries := flag.Int("retries", 3, "When url is provided, number of retries of a failed fetch before recording a gap.")
+	backoff := flag.Duration("backoff", 100*time.Millisecond, "When url is provided, delay before retrying a failed fetch, doubled on each retry.")
 	steps := flag.Int("steps", 100, "Number of values to plot.")
+	history := flag.Int("history", 0, "Number of values kept per field to scroll back through with the keyboard. Defaults to the number of steps.")
+	aggregate := flag.Bool("aggregate", false, "Read raw events from stdin and plot statistics of the events received during each interval"+
+		" (see AGGREGATES).")
 	rows := flag.Int("rows", 0, "Limits the height of the graph output.")
+	timeField := flag.String("time-field", "", "JSON field path holding the time of each sample as a Unix timestamp or RFC 3339 date."+
+		" Time of reception is used if not specified.")
+	text := flag.Bool("text", false, "Render graphs with text characters even if the terminal supports graphics.")
+	outputPath := flag.String("output", "", "Write the graphs to a file instead of the terminal: a PNG of the latest state,"+
+		" a numbered PNG per second if the path contains a %d verb (eg: frame-%05d.png), or an animated GIF of the session"+
+		" written as it goes if it ends with .gif.")
+	size := flag.String("size", "1280x720", "When output is provided, size of the images in pixels.")
+	httpAddr := flag.String("http", "", "Serve the graphs as a web page with live updates on this address (eg: :8080)."+
+		" The series are streamed as server-sent events on /events and the graphs served as a PNG on /graph.png.")
+	configFile := flag.String("config", "", "YAML or JSON file describing the dashboard. Flags and field specs given on the"+
+		" command line take precedence.")
 	flag.Parse()
 
-	if !term.HasGraphicsSupport() {
-		fatal("iTerm2, Kitty, or DRCS Sixel graphics required")
+	var specs []data.Spec
+	if *configFile != "" {
+		cfg, err := loadConfig(*configFile)
+		if err != nil {
+			fatal("Cannot load config: ", err)
+		}
+		set := map[string]bool{}
+		flag.Visit(func(f *flag.Flag) {
+			set[f.Name] = true
+		})
+		for name, value := range cfg.flags() {
+			if !set[name] {
+				if err := flag.Set(name, value); err != nil {
+					fatal("Invalid config: ", name, ": ", err)
+				}
+			}
+		}
+		if !set["source"] {
+			for name, url := range cfg.Sources {
+				if err := sources.Set(name + "=" + url); err != nil {
+					fatal("Invalid config: ", err)
+				}
+			}
+		}
+		if !set["header"] {
+			for name, value := range cfg.Source.Headers {
+				if err := headers.Set(name + ": " + value); err != nil {
+					fatal("Invalid config: ", err)
+				}
+			}
+		}
+		if len(flag.Args()) == 0 {
+			if specs, err = cfg.specs(); err != nil {
+				fatal("Cannot parse spec: ", err)
+			}
+		}
 	}
-	if os.Getenv("TERM") == "screen" {
-		fatal("screen and tmux not supported")
+
+	var out output
+	if *outputPath != "" {
+		width, height, err := parseSize(*size)
+		if err != nil {
+			fatal(err)
+		}
+		if out, err = newOutput(*outputPath, width, height); err != nil {
+			fatal(err)
+		}
 	}
 
-	if len(flag.Args()) == 0 {
+	// Fallback to text rendering when no graphics protocol is available.
+	draw := render
+	if *text || !term.HasGraphicsSupport() {
+		draw = renderText
+	}
+	// When not writing to a terminal (eg: CI logs), only render the final state.
+	interactive := terminal.IsTerminal(os.Stdout)
+
+	if len(flag.Args()) > 0 {
+		var err error
+		if specs, err = data.ParseSpec(flag.Args()); err != nil {
+			fatal("Cannot parse spec: ", err)
+		}
+	}
+	if len(specs) == 0 {
 		flag.Usage()
 		os.Exit(1)
 	}
-
-	specs, err := data.ParseSpec(flag.Args())
-	if err != nil {
-		fatal("Cannot parse spec: ", err)
+	stored := *steps
+	if *history > stored {
+		stored = *history
+	}
+	given := 0
+	for _, v := range []string{*url, *command, *file, *replay} {
+		if v != "" {
+			given++
+		}
+	}
+	if given > 1 {
+		fatal("only one of --url, --exec, --file and --replay can be used")
+	}
+	if *replay != "" && len(sources) > 0 {
+		fatal("--source cannot be used with --replay, the recorded sources are replayed")
+	}
+	if *aggregate && given > 0 {
+		fatal("--aggregate only applies to events read from stdin")
+	}
+	// The default source is only needed by fields not naming a source.
+	needDefault := false
+	for _, spec := range specs {
+		for _, f := range spec.Fields {
+			if f.Source == "" {
+				needDefault = true
+			} else if _, found := sources[f.Source]; !found && *replay == "" {
+				fatal(fmt.Sprintf("unknown source %s in %s@%s", f.Source, f.Source, f.Name))
+			}
+		}
+	}
+	opts := data.HTTPOptions{
+		Interval: *interval,
+		Retries:  *retries,
+		Backoff:  *backoff,
+		Timeout:  *timeout,
+		Method:   strings.ToUpper(*method),
+		Header:   http.Header(headers),
+		CertFile: *cert,
+		KeyFile:  *key,
+		CAFile:   *cacert,
+	}
+	var err error
+	if opts.Body, err = readArg(*body); err != nil {
+		fatal("Cannot read data: ", err)
+	}
+	if opts.BearerToken, err = readArg(*bearerToken); err != nil {
+		fatal("Cannot read bearer token: ", err)
+	}
+	opts.BearerToken = strings.TrimSpace(opts.BearerToken)
+	if *user != "" {
+		opts.Username, opts.Password, _ = strings.Cut(*user, ":")
+	}
+	comma, isTable := separators[*format]
+	if !isTable && *format != "json" && *format != "prometheus" {
+		fatal("invalid format: ", *format)
+	}
+	// openFile returns the points of the file at path.
+	openFile := func(path string) *data.Points {
+		if isTable {
+			return data.FromFileCSV(path, comma, *backfill, stored)
+		}
+		if *format != "json" {
+			fatal(fmt.Sprintf("format %s is not supported for files", *format))
+		}
+		return data.FromFile(path, *backfill, stored)
+	}
+	// openExec returns the points of the output of command.
+	openExec := func(command string) *data.Points {
+		if *format != "json" {
+			fatal(fmt.Sprintf("format %s is not supported for commands", *format))
+		}
+		return data.FromExec(command, *interval, *timeout, stored)
+	}
+	// open returns the points of the URL, of the command prefixed by exec: or
+	// of the file prefixed by file:.
+	open := func(url string) *data.Points {
+		if strings.HasPrefix(url, "exec:") {
+			return openExec(strings.TrimPrefix(url, "exec:"))
+		}
+		if strings.HasPrefix(url, "file:") {
+			return openFile(strings.TrimPrefix(url, "file:"))
+		}
+		var dp *data.Points
+		var err error
+		switch *format {
+		case "json":
+			dp, err = data.FromHTTP(url, opts, stored)
+		case "prometheus":
+			dp, err = data.FromPrometheus(url, opts, stored)
+		default:
+			fatal(fmt.Sprintf("format %s is not supported for urls", *format))
+		}
+		if err != nil {
+			fatal(fmt.Sprintf("Cannot fetch %s: %v", url, err))
+		}
+		return dp
 	}
 	var dp *data.Points
 	if *url != "" {
-		dp = data.FromHTTP(*url, *interval, *steps)
+		dp = open(*url)
+	} else if *command != "" {
+		dp = openExec(*command)
+	} else if *file != "" {
+		dp = openFile(*file)
+	} else if *replay != "" {
+		if *format != "json" {
+			fatal(fmt.Sprintf("format %s cannot be used with --replay", *format))
+		}
+		var err error
+		if dp, err = data.FromReplay(*replay, *replaySpeed, stored); err != nil {
+			fatal("Cannot replay: ", err)
+		}
+	} else if !needDefault {
+		dp = &data.Points{Size: stored}
 	} else if !terminal.IsTerminal(os.Stdin) {
-		dp = data.FromStdin(*steps)
+		if *aggregate {
+			if *timeField != "" {
+				fatal("--time-field cannot be used with --aggregate")
+			}
+			if *format != "json" {
+				fatal(fmt.Sprintf("format %s cannot be used with --aggregate", *format))
+			}
+			var err error
+			if dp, err = data.FromStdinEvents(specs, *interval, stored); err != nil {
+				fatal("Cannot parse spec: ", err)
+			}
+		} else if isTable {
+			dp = data.FromStdinCSV(comma, stored)
+		} else if *format == "json" {
+			dp = data.FromStdin(stored)
+		} else {
+			fatal(fmt.Sprintf("format %s is not supported for stdin", *format))
+		}
 	} else {
-		fatal("neither --url nor stdin is provided")
+		fatal("neither --url, --exec, --file nor stdin is provided")
+	}
+	if len(sources) > 0 {
+		dp.Sources = map[string]data.Getter{}
+		for name, url := range sources {
+			dp.Sources[name] = open(url).Source
+		}
+	}
+	dp.TimeField = *timeField
+	if *record != "" {
+		f, err := os.Create(*record)
+		if err != nil {
+			fatal("Cannot record: ", err)
+		}
+		defer f.Close()
+		dp.Record = f
 	}
 	dash := graph.Dash{
 		Specs: specs,
 		Data:  dp,
+		View:  graph.View{Steps: *steps},
+	}
+
+	if *httpAddr != "" {
+		go func(dash graph.Dash) {
+			if err := serveWeb(*httpAddr, dash); err != nil {
+				fatal("Cannot serve web dashboard: ", err)
+			}
+		}(dash)
+	}
+
+	var keys <-chan term.Key
+	if interactive && out == nil {
+		// Keyboard controls are optional, the terminal may not support them.
+		var err error
+		if keys, stopKeys, err = term.Keys(); err != nil {
+			keys, stopKeys = nil, nil
+		}
+	}
+
+	// quit is closed once the user asked to quit.
+	quit := make(chan struct{})
+	var quitOnce sync.Once
+	stop := func() {
+		quitOnce.Do(func() {
+			close(quit)
+			dp.Close()
+		})
 	}
 
 	wg := &sync.WaitGroup{}
@@ -78,10 +372,28 @@ func main() {
 		defer t.Stop()
 		c := make(chan os.Signal, 2)
 		signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+		resize := make(chan os.Signal, 1)
+		notifyResize(resize)
+		defer signal.Stop(resize)
 		i := 0
 		for {
 			select {
 			case <-t.C:
+				if out != nil {
+					if err := out.Frame(dash); err != nil {
+						fatal("Cannot write output: ", err)
+					}
+					continue
+				}
+				if !interactive {
+					continue
+				}
+				if !term.Visible() {
+					// Do not draw over other tmux panes, the graph is drawn
+					// again as soon as the pane is displayed.
+					term.ClearImages()
+					continue
+				}
 				if i == 0 {
 					prepare(*rows)
 					defer cleanup(*rows)
@@ -92,15 +404,48 @@ func main() {
 					term.ClearScrollback()
 				}
 				term.CursorSavePosition()
-				render(dash, *rows)
+				draw(dash, *rows)
+				term.CursorRestorePosition()
+			case <-resize:
+				if i == 0 || out != nil || !interactive {
+					continue
+				}
+				// Start over with a screen area fitting the new size.
+				term.ResetSize()
+				term.ClearImages()
+				term.Clear()
+				prepare(*rows)
+				term.CursorSavePosition()
+				draw(dash, *rows)
+				term.CursorRestorePosition()
+			case k := <-keys:
+				if k == 'q' {
+					stop()
+					continue
+				}
+				if !handleKey(&dash, k) || i == 0 || !term.Visible() {
+					continue
+				}
+				term.CursorSavePosition()
+				draw(dash, *rows)
 				term.CursorRestorePosition()
 			case <-exit:
+				if out != nil {
+					err := out.Frame(dash)
+					if err == nil {
+						err = out.Close()
+					}
+					if err != nil {
+						fatal("Cannot write output: ", err)
+					}
+					return
+				}
 				if i == 0 {
-					render(dash, *rows)
+					draw(dash, *rows)
 				}
 				return
 			case <-c:
-				dp.Close()
+				stop()
 				signal.Stop(c)
 			}
 		}
@@ -109,9 +454,22 @@ func main() {
 	if err := dp.Run(specs); err != nil {
 		fatal("Data source error: ", err)
 	}
+	if *replay != "" && interactive && out == nil {
+		// Keep the replayed session displayed until the user quits.
+		<-quit
+	}
+	if stopKeys != nil {
+		stopKeys()
+	}
 }
 
+// stopKeys restores the terminal input mode when keyboard controls are on.
+var stopKeys func()
+
 func fatal(a ...interface{}) {
+	if stopKeys != nil {
+		stopKeys()
+	}
 	fmt.Println(append([]interface{}{"jplot: "}, a...)...)
 	os.Exit(1)
 }
@@ -155,3 +513,19 @@ func render(dash graph.Dash, rows int) {
 		fatal(fmt.Sprintf("cannot render graph: %v", err.Error()))
 	}
 }
+
+func renderText(dash graph.Dash, rows int) {
+	cols, height, err := term.Cells()
+	if err != nil {
+		// Not a terminal (eg: CI logs), use a common size and terminate the
+		// output with a new line.
+		cols, height = 80, 25
+		defer fmt.Println()
+	}
+	if rows == 0 {
+		rows = height - 1
+	}
+	if err := dash.RenderText(os.Stdout, cols-1, rows); err != nil {
+		fatal(fmt.Sprintf("cannot render graph: %v", err.Error()))
+	}
+}
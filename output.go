@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/jplot/graph"
+)
+
+// background is painted behind exported graphs, transparent in terminals.
+var background = color.RGBA{R: 30, G: 30, B: 30, A: 255}
+
+// output writes dashboard frames to files instead of the terminal.
+type output interface {
+	// Frame renders the current state of dash.
+	Frame(dash graph.Dash) error
+	// Close flushes the pending frames.
+	Close() error
+}
+
+// newOutput returns the output matching path:
+//   - a path containing a %d verb (eg: frame-%05d.png) writes a numbered PNG
+//     for each frame,
+//   - a path ending with .gif appends each frame to an animated GIF,
+//   - any other path is overwritten with a PNG of the latest frame.
+func newOutput(path string, width, height int) (output, error) {
+	switch {
+	case strings.Contains(path, "%"):
+		if got := fmt.Sprintf(path, 0); strings.Contains(got, "%!") {
+			return nil, fmt.Errorf("invalid output pattern: %s", path)
+		}
+		return &sequenceOutput{pattern: path, width: width, height: height}, nil
+	case strings.EqualFold(filepath.Ext(path), ".gif"):
+		return &gifOutput{path: path, width: width, height: height}, nil
+	}
+	return &snapshotOutput{path: path, width: width, height: height}, nil
+}
+
+// parseSize parses a size in the WIDTHxHEIGHT form.
+func parseSize(s string) (width, height int, err error) {
+	if _, err = fmt.Sscanf(s, "%dx%d", &width, &height); err != nil || width <= 0 || height <= 0 {
+		return 0, 0, fmt.Errorf("invalid size: %s", s)
+	}
+	return width, height, nil
+}
+
+// frame renders dash on an opaque background.
+func frame(dash graph.Dash, width, height int) (*image.RGBA, error) {
+	img, err := dash.Image(width, height)
+	if err != nil {
+		return nil, err
+	}
+	canvas := image.NewRGBA(img.Bounds())
+	draw.Draw(canvas, canvas.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+	draw.Draw(canvas, canvas.Bounds(), img, image.Point{}, draw.Over)
+	return canvas, nil
+}
+
+// writePNG atomically writes img to path so readers never see a partial file.
+func writePNG(path string, img image.Image) error {
+	f, err := os.CreateTemp(filepath.Dir(path), ".jplot-*.png")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	// Temporary files are only readable by their owner.
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+type snapshotOutput struct {
+	path          string
+	width, height int
+}
+
+func (o *snapshotOutput) Frame(dash graph.Dash) error {
+	img, err := frame(dash, o.width, o.height)
+	if err != nil {
+		return err
+	}
+	return writePNG(o.path, img)
+}
+
+func (o *snapshotOutput) Close() error {
+	return nil
+}
+
+type sequenceOutput struct {
+	pattern       string
+	width, height int
+	n             int
+}
+
+func (o *sequenceOutput) Frame(dash graph.Dash) error {
+	img, err := frame(dash, o.width, o.height)
+	if err != nil {
+		return err
+	}
+	o.n++
+	return writePNG(fmt.Sprintf(o.pattern, o.n), img)
+}
+
+func (o *sequenceOutput) Close() error {
+	return nil
+}
+
+// gifOutput streams frames to an animated GIF. The trailer is written after
+// each frame and overwritten by the next one, so the file is a complete GIF
+// whenever the process exits.
+type gifOutput struct {
+	path          string
+	width, height int
+	f             *os.File
+}
+
+// gifLoop is the application extension making the animation loop forever.
+var gifLoop = []byte{0x21, 0xff, 0x0b, 'N', 'E', 'T', 'S', 'C', 'A', 'P', 'E', '2', '.', '0', 0x03, 0x01, 0x00, 0x00, 0x00}
+
+// gifTrailer ends a GIF.
+const gifTrailer = 0x3b
+
+func (o *gifOutput) Frame(dash graph.Dash) error {
+	img, err := frame(dash, o.width, o.height)
+	if err != nil {
+		return err
+	}
+	p := image.NewPaletted(img.Bounds(), palette.Plan9)
+	draw.Draw(p, p.Bounds(), img, image.Point{}, draw.Src)
+	// Encode the frame as a GIF of its own, to be split into the header and
+	// the image block.
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, p, nil); err != nil {
+		return err
+	}
+	b := buf.Bytes()
+	if len(b) < 14 || b[len(b)-1] != gifTrailer {
+		return errors.New("cannot encode gif frame")
+	}
+	headerLen := 13 // signature and logical screen descriptor
+	if flags := b[10]; flags&0x80 != 0 {
+		headerLen += 3 << (flags&0x07 + 1) // global color table
+	}
+	var out []byte
+	if o.f == nil {
+		if o.f, err = os.Create(o.path); err != nil {
+			return err
+		}
+		out = append(append(out, b[:headerLen]...), gifLoop...)
+	} else if _, err := o.f.Seek(-1, io.SeekEnd); err != nil {
+		// Overwrite the trailer.
+		return err
+	}
+	const delay = 100 // frames are rendered every second, in 1/100s
+	out = append(out, 0x21, 0xf9, 0x04, 0x00, delay&0xff, delay>>8, 0x00, 0x00)
+	out = append(out, b[headerLen:]...)
+	_, err = o.f.Write(out)
+	return err
+}
+
+func (o *gifOutput) Close() error {
+	if o.f == nil {
+		return nil
+	}
+	return o.f.Close()
+}
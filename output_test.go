@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"image/gif"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/jplot/data"
+	"github.com/rs/jplot/graph"
+)
+
+func TestGIFOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.gif")
+	o, err := newOutput(path, 64, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := o.(*gifOutput); !ok {
+		t.Fatalf("newOutput(%s) = %T, want *gifOutput", path, o)
+	}
+	dash := graph.Dash{Data: &data.Points{Size: 10}}
+	// The file must be a complete GIF after each frame, not only once closed.
+	for n := 1; n <= 3; n++ {
+		if err := o.Frame(dash); err != nil {
+			t.Fatal(err)
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g, err := gif.DecodeAll(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("frame %d: %v", n, err)
+		}
+		if len(g.Image) != n {
+			t.Errorf("frame %d: %d images, want %d", n, len(g.Image), n)
+		}
+		if g.LoopCount != 0 {
+			t.Errorf("frame %d: loop count %d, want 0 (forever)", n, g.LoopCount)
+		}
+		for i, delay := range g.Delay {
+			if delay != 100 {
+				t.Errorf("frame %d: image %d delay %d, want 100", n, i, delay)
+			}
+		}
+		if g.Config.Width != 64 || g.Config.Height != 32 {
+			t.Errorf("frame %d: size %dx%d, want 64x32", n, g.Config.Width, g.Config.Height)
+		}
+	}
+	if err := o.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewOutput(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{path: "graph.png", want: "*main.snapshotOutput"},
+		{path: "frame-%05d.png", want: "*main.sequenceOutput"},
+		{path: "session.gif", want: "*main.gifOutput"},
+		{path: "SESSION.GIF", want: "*main.gifOutput"},
+		{path: "frame-%s.png", wantErr: true},
+	}
+	for _, tt := range tests {
+		o, err := newOutput(tt.path, 10, 10)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("newOutput(%s): error %v, want error %v", tt.path, err, tt.wantErr)
+			continue
+		}
+		if err == nil {
+			if got := fmt.Sprintf("%T", o); got != tt.want {
+				t.Errorf("newOutput(%s) = %s, want %s", tt.path, got, tt.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,6 @@
+package main
+
+import "os"
+
+// notifyResize is a no-op as Windows has no resize signal.
+func notifyResize(c chan<- os.Signal) {}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var sourceName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// separators maps the table formats to the separator of their values.
+var separators = map[string]rune{
+	"csv": ',',
+	"tsv": '\t',
+}
+
+// sourceFlag holds the named sources given with the repeatable --source flag
+// as name=URL, name=exec:command or name=file:path.
+type sourceFlag map[string]string
+
+func (s sourceFlag) String() string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		names[i] = name + "=" + s[name]
+	}
+	return strings.Join(names, ",")
+}
+
+func (s sourceFlag) Set(v string) error {
+	name, url, ok := strings.Cut(v, "=")
+	if !ok || url == "" {
+		return fmt.Errorf("invalid source %s: expected name=URL, name=exec:command or name=file:path", v)
+	}
+	if !sourceName.MatchString(name) {
+		return fmt.Errorf("invalid source name %s: only letters, digits, - and _ are allowed", name)
+	}
+	if _, found := s[name]; found {
+		return fmt.Errorf("duplicate source %s", name)
+	}
+	s[name] = url
+	return nil
+}
+
+// headerFlag holds the headers given with the repeatable --header flag as
+// "Name: value".
+type headerFlag http.Header
+
+func (h headerFlag) String() string {
+	names := make([]string, 0, len(h))
+	for name, values := range h {
+		for _, v := range values {
+			names = append(names, name+": "+v)
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func (h headerFlag) Set(v string) error {
+	name, value, ok := strings.Cut(v, ":")
+	if name = strings.TrimSpace(name); !ok || name == "" {
+		return fmt.Errorf("invalid header %s: expected \"Name: value\"", v)
+	}
+	http.Header(h).Add(name, strings.TrimSpace(value))
+	return nil
+}
+
+// readArg returns v, or the content of the file it names when prefixed with
+// @.
+func readArg(v string) (string, error) {
+	if !strings.HasPrefix(v, "@") {
+		return v, nil
+	}
+	b, err := os.ReadFile(v[1:])
+	return string(b), err
+}
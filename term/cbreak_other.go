@@ -0,0 +1,10 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package term
+
+import "errors"
+
+func cbreak(fd int) (restore func(), err error) {
+	return nil, errors.New("keyboard input not supported")
+}
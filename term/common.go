@@ -19,15 +19,15 @@ var cellWidth, cellHeight float64
 var termWidth, termHeight int
 
 func HasGraphicsSupport() bool {
-	return os.Getenv("TERM_PROGRAM") == "iTerm.app" ||
+	return itermEnabled ||
 		kittyEnabled ||
 		sixelEnabled
 }
 
 // ClearScrollback clears iTerm2 scrollback.
 func ClearScrollback() {
-	if !sixelEnabled {
-		print(ecsi + "1337;ClearScrollback" + st)
+	if itermEnabled {
+		writeSeq(ecsi + "1337;ClearScrollback" + st)
 	}
 }
 
@@ -40,13 +40,16 @@ type TermSize struct {
 }
 
 func initCellSize() {
+	// Keep the key reader from consuming the answer.
+	ttyMu.Lock()
+	defer ttyMu.Unlock()
 	s, err := terminal.MakeRaw(1)
 	if err != nil {
 		return
 	}
 	defer terminal.Restore(1, s)
 	if sixelEnabled {
-		fmt.Fprint(os.Stdout, "\033[14t")
+		writeSeq("\033[14t")
 		fileSetReadDeadline(os.Stdout, time.Now().Add(time.Second))
 		defer fileSetReadDeadline(os.Stdout, time.Time{})
 		fmt.Fscanf(os.Stdout, "\033[4;%d;%dt", &termHeight, &termWidth)
@@ -54,24 +57,39 @@ func initCellSize() {
 	}
 	if kittyEnabled {
 		// For Kitty terminals, use the standard terminal size query
-		fmt.Fprint(os.Stdout, "\033[14t")
+		writeSeq("\033[14t")
 		fileSetReadDeadline(os.Stdout, time.Now().Add(time.Second))
 		defer fileSetReadDeadline(os.Stdout, time.Time{})
 		fmt.Fscanf(os.Stdout, "\033[4;%d;%dt", &termHeight, &termWidth)
 		return
 	}
-	fmt.Fprint(os.Stdout, ecsi+"1337;ReportCellSize"+st)
+	writeSeq(ecsi + "1337;ReportCellSize" + st)
 	fileSetReadDeadline(os.Stdout, time.Now().Add(time.Second))
 	defer fileSetReadDeadline(os.Stdout, time.Time{})
 	fmt.Fscanf(os.Stdout, "\033]1337;ReportCellSize=%f;%f\033\\", &cellHeight, &cellWidth)
 }
 
+// ResetSize discards the cached sizing information so it is queried again
+// by the next call to Size, typically after the terminal was resized.
+func ResetSize() {
+	cellSizeOnce = sync.Once{}
+	cellWidth, cellHeight = 0, 0
+	termWidth, termHeight = 0, 0
+	// Zooming a tmux pane resizes it, which may hide or show it.
+	visibleChecked = time.Time{}
+}
+
 // Size gathers sizing information of the current session's controling terminal.
 func Size() (size TermSize, err error) {
 	size.Col, size.Row, err = terminal.GetSize(1)
 	if err != nil {
 		return
 	}
+	if width, height := pixelSize(); width > 0 && height > 0 {
+		// Reported by the kernel when supported, including by tmux.
+		size.Width, size.Height = width, height
+		return
+	}
 	cellSizeOnce.Do(initCellSize)
 	if termWidth > 0 && termHeight > 0 {
 		size.Width = int(termWidth/(size.Col-1)) * (size.Col - 1)
@@ -85,6 +103,11 @@ func Size() (size TermSize, err error) {
 	return
 }
 
+// Cells returns the number of columns and rows for the controling terminal.
+func Cells() (cols, rows int, err error) {
+	return terminal.GetSize(1)
+}
+
 // Rows returns the number of rows for the controling terminal.
 func Rows() (rows int, err error) {
 	_, rows, err = terminal.GetSize(1)
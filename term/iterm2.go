@@ -9,12 +9,9 @@ import (
 	"sync"
 )
 
-func init() {
-	if os.Getenv("TERM") == "screen" {
-		ecsi = "\033Ptmux;\033" + ecsi
-		st += "\033\\"
-	}
-}
+var itermEnabled = os.Getenv("TERM_PROGRAM") == "iTerm.app" ||
+	// Set by iTerm2 and preserved by tmux, which overrides TERM_PROGRAM.
+	os.Getenv("LC_TERMINAL") == "iTerm2"
 
 // imageWriter is a writer that write into iTerm2 terminal the PNG data written
 type imageWriter struct {
@@ -41,6 +38,9 @@ func (w *imageWriter) Write(p []byte) (n int, err error) {
 // Close flushes the image to the terminal and close the writer.
 func (w *imageWriter) Close() error {
 	w.once.Do(w.init)
-	fmt.Printf("%s1337;File=preserveAspectRatio=1;width=%dpx;height=%dpx;inline=1:%s%s", ecsi, w.Width, w.Height, w.buf.Bytes(), st)
-	return w.b64enc.Close()
+	if err := w.b64enc.Close(); err != nil {
+		return err
+	}
+	writeSeq(fmt.Sprintf("%s1337;File=preserveAspectRatio=1;width=%dpx;height=%dpx;inline=1:%s%s", ecsi, w.Width, w.Height, w.buf.Bytes(), st))
+	return nil
 }
@@ -0,0 +1,132 @@
+package term
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Key is a key pressed by the user, either a character or one of the
+// special keys below.
+type Key rune
+
+// Special keys, which do not collide with characters.
+const (
+	KeyUp Key = -1 - iota
+	KeyDown
+	KeyRight
+	KeyLeft
+)
+
+// ttyMu serializes reads from the terminal between the key reader and the
+// queries expecting an answer from the terminal.
+var ttyMu sync.Mutex
+
+// Keys switches the controlling terminal to unbuffered input without echo and
+// sends the keys pressed to the returned channel. Stop restores the terminal.
+// Signals such as Ctrl-C are still handled by the terminal.
+func Keys() (keys <-chan Key, stop func(), err error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The descriptor is not taken with Fd, which would switch the tty to
+	// blocking mode and disable the read deadlines below.
+	rc, err := tty.SyscallConn()
+	if err != nil {
+		tty.Close()
+		return nil, nil, err
+	}
+	var restore func()
+	if cerr := rc.Control(func(fd uintptr) {
+		restore, err = cbreak(int(fd))
+	}); cerr != nil {
+		err = cerr
+	}
+	if err != nil {
+		tty.Close()
+		return nil, nil, err
+	}
+	c := make(chan Key, 16)
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		buf := make([]byte, 64)
+		for {
+			// Reads time out regularly so terminal queries can get their
+			// answer, unless the tty does not support deadlines.
+			locked := fileSetReadDeadline(tty, time.Now().Add(100*time.Millisecond)) == nil
+			if locked {
+				ttyMu.Lock()
+			}
+			n, err := tty.Read(buf)
+			if locked {
+				ttyMu.Unlock()
+			}
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if err != nil {
+				if errors.Is(err, os.ErrDeadlineExceeded) {
+					continue
+				}
+				return
+			}
+			for _, k := range parseKeys(buf[:n]) {
+				select {
+				case c <- k:
+				case <-done:
+					return
+				}
+			}
+		}
+	}()
+	var once sync.Once
+	stop = func() {
+		once.Do(func() {
+			close(done)
+			<-exited
+			restore()
+			tty.Close()
+		})
+	}
+	return c, stop, nil
+}
+
+// parseKeys decodes the keys sent by the terminal in b.
+func parseKeys(b []byte) []Key {
+	var keys []Key
+	for len(b) > 0 {
+		// Arrows are sent as ESC [ X, or ESC O X in application mode.
+		if len(b) >= 3 && b[0] == '\033' && (b[1] == '[' || b[1] == 'O') {
+			switch b[2] {
+			case 'A':
+				keys = append(keys, KeyUp)
+			case 'B':
+				keys = append(keys, KeyDown)
+			case 'C':
+				keys = append(keys, KeyRight)
+			case 'D':
+				keys = append(keys, KeyLeft)
+			}
+			// Skip the parameters and final byte of other sequences.
+			i := 2
+			for i < len(b)-1 && (b[i] < 0x40 || b[i] > 0x7e) {
+				i++
+			}
+			b = b[i+1:]
+			continue
+		}
+		r, size := utf8.DecodeRune(b)
+		if r != '\033' && r != utf8.RuneError {
+			keys = append(keys, Key(r))
+		}
+		b = b[size:]
+	}
+	return keys
+}
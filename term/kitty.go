@@ -16,13 +16,17 @@ var kittyCurrentID = 1     // Track current image ID globally
 var kittyFirstImage = true // Track if this is the first image
 
 func init() {
-	if os.Getenv("TERM_PROGRAM") != "iTerm.app" {
+	if !itermEnabled {
 		kittyEnabled = checkKitty()
 	}
 }
 
 // checkKitty detects if the terminal supports Kitty graphics protocol
 func checkKitty() bool {
+	if inTmux || inScreen {
+		// Multiplexers do not forward the response of the outer terminal.
+		return os.Getenv("KITTY_WINDOW_ID") != "" || outerTerm() == "xterm-kitty"
+	}
 	s, err := terminal.MakeRaw(1)
 	if err != nil {
 		return false
@@ -84,12 +88,12 @@ func (w *kittyWriter) Close() error {
 	// Step 1: Display the new image with absolute positioning
 	// a=T (transmit and display), f=100 (PNG), t=d (direct), i=nextID (image ID),
 	// X=0,Y=0 (absolute position), q=2 (suppress responses)
-	fmt.Printf("\033_Ga=T,f=100,t=d,i=%d,X=0,Y=0,q=2;%s\033\\", nextID, b64data)
+	writeSeq(fmt.Sprintf("\033_Ga=T,f=100,t=d,i=%d,X=0,Y=0,q=2;%s\033\\", nextID, b64data))
 
 	// Step 2: Delete the previously displayed image (skip deletion only on very first image)
 	// Remove q=2 from delete to ensure it executes properly
 	if !kittyFirstImage {
-		fmt.Printf("\033_Ga=d,d=i,i=%d,q=2\033\\", kittyCurrentID)
+		writeSeq(fmt.Sprintf("\033_Ga=d,d=i,i=%d,q=2\033\\", kittyCurrentID))
 	}
 	kittyFirstImage = false // After first image, we always delete
 
@@ -98,3 +102,12 @@ func (w *kittyWriter) Close() error {
 
 	return nil
 }
+
+// ClearImages removes the images displayed with the Kitty graphics protocol,
+// which are not erased by clearing the screen.
+func ClearImages() {
+	if kittyEnabled && !kittyFirstImage {
+		writeSeq(fmt.Sprintf("\033_Ga=d,d=i,i=%d,q=2\033\\", kittyCurrentID))
+		kittyFirstImage = true
+	}
+}
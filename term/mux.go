@@ -0,0 +1,89 @@
+package term
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Terminal multiplexers do not forward unknown escape sequences to the outer
+// terminal unless they are wrapped in a DCS passthrough sequence.
+var (
+	inTmux   = os.Getenv("TMUX") != ""
+	inScreen = !inTmux && (os.Getenv("STY") != "" || strings.HasPrefix(os.Getenv("TERM"), "screen"))
+)
+
+// screenMaxDCS is the maximum length of a DCS string accepted by GNU screen.
+const screenMaxDCS = 768
+
+// passthrough wraps seq so a terminal multiplexer forwards it untouched to
+// the outer terminal. Tmux requires the allow-passthrough option to be on.
+func passthrough(seq string) string {
+	switch {
+	case inTmux:
+		return "\033Ptmux;" + strings.ReplaceAll(seq, "\033", "\033\033") + "\033\\"
+	case inScreen:
+		var b strings.Builder
+		for len(seq) > 0 {
+			n := len(seq)
+			if n > screenMaxDCS {
+				n = screenMaxDCS
+			}
+			b.WriteString("\033P" + seq[:n] + "\033\\")
+			seq = seq[n:]
+		}
+		return b.String()
+	}
+	return seq
+}
+
+// writeSeq writes the escape sequence seq to the terminal.
+func writeSeq(seq string) {
+	os.Stdout.WriteString(passthrough(seq))
+}
+
+// outerTerm returns the TERM of the terminal the tmux client runs in, as
+// tmux overrides the TERM of its panes.
+func outerTerm() string {
+	if !inTmux {
+		return os.Getenv("TERM")
+	}
+	return tmuxFormat("#{client_termname}")
+}
+
+// visibleTTL is how long the visibility of the tmux pane is cached, so tmux
+// is not queried on every render.
+const visibleTTL = time.Second
+
+var visible bool
+var visibleChecked time.Time
+
+// Visible returns false when jplot runs in a tmux pane that is not currently
+// displayed, in which case nothing should be drawn as images sent through
+// passthrough would end up over other panes.
+func Visible() bool {
+	if !inTmux {
+		return true
+	}
+	if time.Since(visibleChecked) < visibleTTL {
+		return visible
+	}
+	// Pane is hidden when its window is not the current one, the session is
+	// detached, or another pane of the window is zoomed.
+	state := tmuxFormat("#{window_active}#{?session_attached,1,0}#{?window_zoomed_flag,#{pane_active},1}")
+	visible, visibleChecked = state == "" || state == "111", time.Now()
+	return visible
+}
+
+func tmuxFormat(format string) string {
+	args := []string{"display-message", "-p"}
+	if pane := os.Getenv("TMUX_PANE"); pane != "" {
+		args = append(args, "-t", pane)
+	}
+	out, err := exec.Command("tmux", append(args, format)...).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
@@ -14,9 +14,24 @@ import (
 
 var sixelEnabled = false
 
+// sixelPassthrough is set when the outer terminal supports sixel but tmux
+// does not, in which case images are sent through passthrough.
+var sixelPassthrough = false
+
+// sixelTerms are terminals known to support sixel by their TERM.
+var sixelTerms = map[string]bool{
+	"foot":          true,
+	"mlterm":        true,
+	"yaft-256color": true,
+	"contour":       true,
+}
+
 func init() {
-	if os.Getenv("TERM_PROGRAM") != "iTerm.app" {
+	if !itermEnabled {
 		sixelEnabled = checkSixel()
+		if !sixelEnabled && inTmux && sixelTerms[outerTerm()] {
+			sixelEnabled, sixelPassthrough = true, true
+		}
 	}
 }
 
@@ -76,13 +91,11 @@ type sixelWriter struct {
 	Height int
 
 	once sync.Once
-	enc  *sixel.Encoder
 	buf  *bytes.Buffer
 }
 
 func (w *sixelWriter) init() {
 	w.buf = &bytes.Buffer{}
-	w.enc = sixel.NewEncoder(os.Stdout)
 }
 
 // Write writes the PNG image data into the imageWriter buffer.
@@ -98,5 +111,13 @@ func (w *sixelWriter) Close() error {
 	if err != nil {
 		return err
 	}
-	return w.enc.Encode(img)
+	if !sixelPassthrough {
+		return sixel.NewEncoder(os.Stdout).Encode(img)
+	}
+	out := &bytes.Buffer{}
+	if err := sixel.NewEncoder(out).Encode(img); err != nil {
+		return err
+	}
+	writeSeq(out.String())
+	return nil
 }
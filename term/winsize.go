@@ -0,0 +1,16 @@
+//go:build !windows
+// +build !windows
+
+package term
+
+import "golang.org/x/sys/unix"
+
+// pixelSize returns the size of the terminal in pixels as reported by the
+// TIOCGWINSZ ioctl, or zeros if unknown.
+func pixelSize() (width, height int) {
+	ws, err := unix.IoctlGetWinsize(1, unix.TIOCGWINSZ)
+	if err != nil {
+		return 0, 0
+	}
+	return int(ws.Xpixel), int(ws.Ypixel)
+}
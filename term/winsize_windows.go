@@ -0,0 +1,5 @@
+package term
+
+func pixelSize() (width, height int) {
+	return 0, 0
+}
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/jplot/graph"
+)
+
+const webPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>jplot</title>
+<style>
+html, body { margin: 0; height: 100%; background: #1e1e1e; overflow: hidden; }
+canvas { display: block; width: 100%; height: 100%; }
+#status { position: fixed; bottom: 4px; right: 8px; color: #b4b4b4; font: 12px sans-serif; }
+</style>
+</head>
+<body>
+<canvas id="graph"></canvas>
+<div id="status"></div>
+<script>
+const canvas = document.getElementById("graph");
+const status = document.getElementById("status");
+const ctx = canvas.getContext("2d");
+const text = "#b4b4b4";
+let dash = null;
+let connected = false;
+
+function si(v) {
+  if (v === null) return "-";
+  for (const [n, prefix] of [[1e12, "T"], [1e9, "G"], [1e6, "M"], [1e3, "k"]]) {
+    if (Math.abs(v) >= n) return +(v / n).toFixed(2) + " " + prefix;
+  }
+  return String(+v.toFixed(2));
+}
+
+function clock(ms) {
+  return new Date(ms).toTimeString().slice(0, 8);
+}
+
+// segments calls fn with the start and end index of each run of values of
+// the same state.
+function segments(s, fn) {
+  for (let i = 0; i < s.values.length;) {
+    let j = i + 1;
+    while (j < s.values.length && s.values[j] === s.values[i]) j++;
+    if (s.values[i] !== null) fn(i, j);
+    i = j;
+  }
+}
+
+function drawGraph(g, top, width, height, xmin, xmax) {
+  const lines = g.series.filter(s => !s.states && !s.marker);
+  const bands = g.series.filter(s => s.states);
+  const left = 10, right = width - 60;
+  const plotTop = top + (g.title ? 36 : 20), bottom = top + height - 20;
+  const bandHeight = lines.length ? Math.min(bands.length * 16, height / 2) : bottom - plotTop;
+  const plotBottom = bottom - (bands.length ? bandHeight : 0);
+  const x = t => left + (t - xmin) / (xmax - xmin) * (right - left);
+  ctx.font = "12px sans-serif";
+  ctx.textBaseline = "middle";
+  if (g.title) {
+    ctx.fillStyle = text;
+    ctx.textAlign = "center";
+    ctx.fillText(g.title, width / 2, top + 10);
+  }
+  // Legend.
+  ctx.textAlign = "left";
+  let lx = left;
+  for (const s of g.series) {
+    if (s.marker) continue;
+    ctx.fillStyle = s.color;
+    ctx.fillRect(lx, plotTop - 16, 8, 8);
+    ctx.fillStyle = text;
+    ctx.fillText(s.legend, lx + 12, plotTop - 12);
+    lx += ctx.measureText(s.legend).width + 28;
+  }
+  // Time axis.
+  ctx.strokeStyle = text;
+  ctx.beginPath();
+  ctx.moveTo(left, bottom + 0.5);
+  ctx.lineTo(right, bottom + 0.5);
+  ctx.stroke();
+  ctx.fillStyle = text;
+  for (const [t, align] of [[xmin, "left"], [(xmin + xmax) / 2, "center"], [xmax, "right"]]) {
+    ctx.textAlign = align;
+    ctx.fillText(clock(t), x(t), bottom + 10);
+  }
+  // Markers.
+  ctx.strokeStyle = "rgba(200, 200, 200, 0.4)";
+  for (const s of g.series.filter(s => s.marker)) {
+    s.values.forEach((v, i) => {
+      if (v > 0) {
+        ctx.beginPath();
+        ctx.moveTo(Math.round(x(s.times[i])) + 0.5, plotTop);
+        ctx.lineTo(Math.round(x(s.times[i])) + 0.5, bottom);
+        ctx.stroke();
+      }
+    });
+  }
+  // Lines, with gaps for null values.
+  if (lines.length) {
+    let ymin = Infinity, ymax = -Infinity;
+    for (const s of lines) {
+      for (const v of s.values) {
+        if (v !== null) {
+          ymin = Math.min(ymin, v);
+          ymax = Math.max(ymax, v);
+        }
+      }
+    }
+    if (ymin > ymax) ymin = ymax = 0;
+    if (ymin === ymax) ymin -= 1, ymax += 1;
+    const y = v => plotBottom - (v - ymin) / (ymax - ymin) * (plotBottom - plotTop);
+    ctx.textAlign = "left";
+    ctx.fillStyle = text;
+    ctx.strokeStyle = "rgba(180, 180, 180, 0.15)";
+    for (let i = 0; i <= 4; i++) {
+      const v = ymin + (ymax - ymin) * i / 4;
+      ctx.beginPath();
+      ctx.moveTo(left, Math.round(y(v)) + 0.5);
+      ctx.lineTo(right, Math.round(y(v)) + 0.5);
+      ctx.stroke();
+      ctx.fillText(si(v), right + 6, y(v));
+    }
+    ctx.lineWidth = 1.5;
+    for (const s of lines) {
+      ctx.strokeStyle = s.color;
+      ctx.beginPath();
+      let pen = false;
+      s.values.forEach((v, i) => {
+        if (v === null) {
+          pen = false;
+          return;
+        }
+        pen ? ctx.lineTo(x(s.times[i]), y(v)) : ctx.moveTo(x(s.times[i]), y(v));
+        pen = true;
+      });
+      ctx.stroke();
+    }
+    ctx.lineWidth = 1;
+  }
+  // State bands under the lines, or filling the graph.
+  const rowHeight = bandHeight / bands.length;
+  bands.forEach((s, n) => {
+    const rowTop = plotBottom + n * rowHeight;
+    segments(s, (i, j) => {
+      const from = Math.max(x(s.times[i]), left);
+      const to = j < s.times.length ? x(s.times[j]) : right;
+      ctx.globalAlpha = 0.8;
+      ctx.fillStyle = s.colors[s.values[i]];
+      ctx.fillRect(from, rowTop, to - from, rowHeight - 1);
+      ctx.globalAlpha = 1;
+      const name = s.states[s.values[i]];
+      if (ctx.measureText(name).width + 4 < to - from) {
+        ctx.fillStyle = "#fff";
+        ctx.textAlign = "right";
+        ctx.fillText(name, to - 2, rowTop + rowHeight / 2);
+      }
+    });
+  });
+}
+
+function draw() {
+  const width = window.innerWidth, height = window.innerHeight;
+  canvas.width = Math.round(width * devicePixelRatio);
+  canvas.height = Math.round(height * devicePixelRatio);
+  ctx.setTransform(devicePixelRatio, 0, 0, devicePixelRatio, 0, 0);
+  ctx.clearRect(0, 0, width, height);
+  status.textContent = connected ? (dash && dash.status) || "" : "disconnected";
+  if (!dash) return;
+  let xmin = dash.start, xmax = dash.end;
+  if (xmin === xmax) xmin -= 1000;
+  const total = dash.graphs.reduce((sum, g) => sum + (g.height || 1), 0);
+  let top = 0;
+  for (const g of dash.graphs) {
+    const h = height * (g.height || 1) / total;
+    drawGraph(g, top, width, h, xmin, xmax);
+    top += h;
+  }
+}
+
+const events = new EventSource("events");
+events.onmessage = e => {
+  dash = JSON.parse(e.data);
+  draw();
+};
+events.onopen = () => {
+  connected = true;
+  draw();
+};
+events.onerror = () => {
+  connected = false;
+  draw();
+};
+window.onresize = draw;
+</script>
+</body>
+</html>
+`
+
+// webInterval is the delay between two updates of the web dashboard.
+const webInterval = time.Second
+
+// maxWebSize is the maximum width and height of the images served by
+// /graph.png.
+const maxWebSize = 4096
+
+// webDash is the state of the dashboard sent to the web page. Times are Unix
+// milliseconds.
+type webDash struct {
+	Status string     `json:"status,omitempty"`
+	Start  int64      `json:"start"`
+	End    int64      `json:"end"`
+	Graphs []webGraph `json:"graphs"`
+}
+
+type webGraph struct {
+	Title  string      `json:"title,omitempty"`
+	Height int         `json:"height,omitempty"`
+	Series []webSeries `json:"series"`
+}
+
+// webSeries is a series sent to the web page, gaps being null values. The
+// values of fields holding booleans or strings are the index of their state
+// in States, drawn with the color of the same index in Colors.
+type webSeries struct {
+	Name   string     `json:"name"`
+	Source string     `json:"source,omitempty"`
+	Legend string     `json:"legend"`
+	Color  string     `json:"color,omitempty"`
+	Marker bool       `json:"marker,omitempty"`
+	Times  []int64    `json:"times"`
+	Values []*float64 `json:"values"`
+	States []string   `json:"states,omitempty"`
+	Colors []string   `json:"colors,omitempty"`
+}
+
+// pngCache renders the PNG images of the dashboard one at a time, at most
+// once per update for each size.
+type pngCache struct {
+	dash   graph.Dash
+	mu     sync.Mutex
+	images map[image.Point]cachedPNG
+}
+
+type cachedPNG struct {
+	b []byte
+	t time.Time
+}
+
+func (c *pngCache) get(width, height int) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for size, img := range c.images {
+		if time.Since(img.t) >= webInterval {
+			delete(c.images, size)
+		}
+	}
+	size := image.Point{X: width, Y: height}
+	if img, found := c.images[size]; found {
+		return img.b, nil
+	}
+	img, err := frame(c.dash, width, height)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	// Requests waiting for a large render get its result.
+	c.images[size] = cachedPNG{b: buf.Bytes(), t: time.Now()}
+	return buf.Bytes(), nil
+}
+
+// webDimension parses the width or height v of an image, clamped to
+// maxWebSize, or returns def if invalid.
+func webDimension(v string, def int) int {
+	n, err := strconv.Atoi(v)
+	switch {
+	case err != nil || n <= 0:
+		return def
+	case n > maxWebSize:
+		return maxWebSize
+	}
+	return n
+}
+
+// serveWeb serves the dashboard as a web page drawing the series sent every
+// second as server-sent events. The dashboard is also served as a PNG image.
+func serveWeb(addr string, dash graph.Dash) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, webPage)
+	})
+	images := &pngCache{dash: dash, images: map[image.Point]cachedPNG{}}
+	mux.HandleFunc("/graph.png", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		b, err := images.get(webDimension(q.Get("width"), 1280), webDimension(q.Get("height"), 720))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Write(b)
+	})
+	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming not supported", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-store")
+		t := time.NewTicker(webInterval)
+		defer t.Stop()
+		for {
+			b, err := json.Marshal(newWebDash(dash))
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", b); err != nil {
+				return
+			}
+			flusher.Flush()
+			select {
+			case <-t.C:
+			case <-r.Context().Done():
+				return
+			}
+		}
+	})
+	return http.ListenAndServe(addr, mux)
+}
+
+// newWebDash returns the state of dash sent to the web page.
+func newWebDash(dash graph.Dash) webDash {
+	start, end := dash.Window()
+	if start.IsZero() {
+		// No data received yet.
+		start, end = time.Now(), time.Now()
+	}
+	d := webDash{
+		Status: dash.Status(),
+		Start:  start.UnixMilli(),
+		End:    end.UnixMilli(),
+		Graphs: make([]webGraph, 0, len(dash.Specs)),
+	}
+	for _, spec := range dash.Specs {
+		g := webGraph{
+			Title:  spec.Title,
+			Height: spec.Height,
+			Series: make([]webSeries, 0, len(spec.Fields)),
+		}
+		lines := 0
+		for _, f := range dash.Data.Fields(spec) {
+			times, vals := dash.Series(f)
+			s := webSeries{
+				Name:   f.Name,
+				Source: f.Source,
+				Legend: dash.Legend(f),
+				Marker: f.IsMarker,
+				Times:  make([]int64, len(times)),
+				Values: make([]*float64, len(vals)),
+				States: dash.Data.States(f.ID),
+			}
+			if s.States != nil {
+				s.Colors = graph.StateColors(s.States)
+			} else if !f.IsMarker {
+				// Same colors as the terminal.
+				s.Color = graph.Color(f, lines)
+				lines++
+			}
+			for i := range times {
+				s.Times[i] = times[i].UnixMilli()
+				// JSON has no representation for NaN and infinities.
+				if !math.IsNaN(vals[i]) && !math.IsInf(vals[i], 0) {
+					s.Values[i] = &vals[i]
+				}
+			}
+			g.Series = append(g.Series, s)
+		}
+		d.Graphs = append(d.Graphs, g)
+	}
+	return d
+}